@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	_ "github.com/chirino/graphql-4-apis/internal/cmd/diff"
 	_ "github.com/chirino/graphql-4-apis/internal/cmd/new"
+	_ "github.com/chirino/graphql-4-apis/internal/cmd/report"
 	graphql_4_apis "github.com/chirino/graphql-4-apis/internal/cmd/root"
+	_ "github.com/chirino/graphql-4-apis/internal/cmd/schema"
 	_ "github.com/chirino/graphql-4-apis/internal/cmd/serve"
+	_ "github.com/chirino/graphql-4-apis/internal/cmd/validate"
 )
 
 func Main() {
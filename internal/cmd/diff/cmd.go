@@ -0,0 +1,130 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/chirino/graphql-4-apis/internal/cmd/root"
+	api "github.com/chirino/graphql-4-apis/internal/cmd/serve"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/chirino/graphql/schema"
+	"github.com/spf13/cobra"
+)
+
+var (
+	Command = &cobra.Command{
+		Use:   "diff PREVIOUS NEXT",
+		Short: "Classifies the GraphQL schema changes between two openapi document revisions",
+		Long: `Builds the GraphQL schema of two openapi documents, without accessing the API, and classifies
+the changes as breaking, dangerous or safe.  Either document can also be a GraphQL schema file
+(.graphql, .graphqls or .gql), like the one printed by the schema command.
+
+The generation options are loaded from the --config file if it exists.  The command exits with
+status 1 when a change is at least as critical as the --fail-on level.`,
+		Args: cobra.ExactArgs(2),
+		Run:  run,
+	}
+	ConfigFile = ""
+	Format     = "table"
+	FailOn     = "breaking"
+)
+
+func init() {
+	Command.Flags().StringVar(&ConfigFile, "config", "graphql-4-apis.yaml", "path to the config file to load")
+	Command.Flags().StringVar(&Format, "format", "table", "output format: table or json")
+	Command.Flags().StringVar(&FailOn, "fail-on", "breaking", "exit with status 1 on changes of this criticality or higher: safe, dangerous, breaking or none")
+	root.Command.AddCommand(Command)
+}
+
+func run(_ *cobra.Command, args []string) {
+	vebosityFmt := "%v"
+	if root.Verbose {
+		vebosityFmt = "%+v\n"
+	}
+
+	if Format != "table" && Format != "json" {
+		log.Fatalf("invalid format: %s", Format)
+	}
+	threshold := apis.Criticality(-1)
+	if FailOn != "none" {
+		var err error
+		threshold, err = apis.ParseCriticality(FailOn)
+		if err != nil {
+			log.Fatalf(vebosityFmt, err)
+		}
+	}
+
+	previous, err := loadSchema(args[0])
+	if err != nil {
+		log.Fatalf(vebosityFmt, err)
+	}
+	next, err := loadSchema(args[1])
+	if err != nil {
+		log.Fatalf(vebosityFmt, err)
+	}
+	changes := apis.DiffSchemas(previous, next)
+
+	if Format == "json" {
+		if changes == nil {
+			changes = []apis.SchemaChange{}
+		}
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			log.Fatalf(vebosityFmt, err)
+		}
+		fmt.Println(string(data))
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "CRITICALITY\tPATH\tDESCRIPTION")
+		for _, change := range changes {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", change.Criticality, change.Path, change.Description)
+		}
+		w.Flush()
+	}
+
+	if FailOn != "none" {
+		for _, change := range changes {
+			if change.Criticality >= threshold {
+				os.Exit(1)
+			}
+		}
+	}
+}
+
+// loadSchema parses a GraphQL schema file, or generates the schema of an openapi document.
+func loadSchema(file string) (*schema.Schema, error) {
+	sdl := ""
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".graphql", ".graphqls", ".gql":
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		sdl = string(data)
+	default:
+		config, err := api.LoadSpecConfig(ConfigFile, file)
+		if err != nil {
+			return nil, err
+		}
+		if config.Webhooks.Enabled {
+			config.WebhookReceiver = apis.NewWebhookReceiver()
+		}
+		engine, err := apis.CreateGatewayEngine(config.Config)
+		if err != nil {
+			return nil, err
+		}
+		sdl = engine.Schema.String()
+	}
+	// both kinds of files are parsed from the SDL so that they are compared the same way.
+	s := schema.New()
+	if err := s.Parse(sdl); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -1,60 +1,350 @@
 package new
 
 import (
+	"bufio"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/chirino/graphql-4-apis/internal/cmd/root"
+	api "github.com/chirino/graphql-4-apis/internal/cmd/serve"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/ghodss/yaml"
 	"github.com/spf13/cobra"
 )
 
 var (
 	Command = &cobra.Command{
-		Use:   "new",
+		Use:   "new DIR",
 		Short: "creates a new project with a default config",
-		Run:   run,
-		Args:  cobra.ExactArgs(1),
+		Long: `Creates a new project with a config file for an openapi document.  The document is loaded to
+pre-fill the API base URL from its servers, and to list its security schemes with placeholders for
+their credentials.  The --overlay option also writes an OpenAPI Overlay listing all the operations,
+so that the ones the GraphQL schema should not expose can be pruned.
+
+The values that are not given with options are asked for when the command runs in a terminal.`,
+		Run:  run,
+		Args: cobra.ExactArgs(1),
 	}
-	ConfigFile = ""
+	SpecFile = ""
+	APIBase  = ""
+	Overlay  = false
 )
 
 func init() {
+	Command.Flags().StringVar(&SpecFile, "spec", "", "path or URL of the openapi document of the API")
+	Command.Flags().StringVar(&APIBase, "api", "", "base URL of the API, defaults to the first server of the openapi document")
+	Command.Flags().BoolVar(&Overlay, "overlay", false, "write an OpenAPI Overlay listing the operations of the openapi document")
 	root.Command.AddCommand(Command)
 }
 
+const (
+	configFileName  = "graphql-4-apis.yaml"
+	schemaFileName  = "graphql-4-apis.schema.json"
+	overlayFileName = "graphql-4-apis.overlay.yaml"
+)
+
 func run(cmd *cobra.Command, args []string) {
+	vebosityFmt := "%v"
+	if root.Verbose {
+		vebosityFmt = "%+v\n"
+	}
+
 	dir := args[0]
-	os.MkdirAll(dir, 0755)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		log.Fatalf(vebosityFmt, err)
+	}
 
-	configFile := filepath.Join(dir, "graphql-4-apis.yaml")
-	err := ioutil.WriteFile(configFile, []byte(`#
-# Configure the host and port the service will listen on
-listen: 0.0.0.0:8080
+	p := &prompter{interactive: isTerminal(os.Stdin), in: bufio.NewReader(os.Stdin)}
+	specFile := SpecFile
+	if specFile == "" {
+		specFile = p.ask("Path or URL of the openapi document", "")
+	}
 
-# Configures how to get the openapi document.  It can be openapi v2 or v3.
-Openapi:
-  URL: openapi.json
-  InsecureClient: false
-  BearerToken:
+	var doc *openapi3.T
+	specLocation := "openapi.json"
+	if specFile != "" {
+		doc, err = apis.LoadOpenApiV2orV3Doc(apis.EndpointOptions{URL: specFile})
+		if err != nil {
+			log.Fatalf("could not load the openapi document %s: "+vebosityFmt, specFile, err)
+		}
+		specLocation, err = projectLocation(dir, specFile)
+		if err != nil {
+			log.Fatalf(vebosityFmt, err)
+		}
+	}
 
-# Configures the base URL that API requests will get issued against.
-APIBase:
-  URL: https://api.crc.testing:6443
-  InsecureClient: true
-  BearerToken:
+	apiBase := APIBase
+	if apiBase == "" && doc != nil {
+		servers := serverURLs(doc, specFile)
+		switch {
+		case len(servers) == 1:
+			apiBase = servers[0]
+		case len(servers) > 1:
+			apiBase = servers[p.choose("API base URL", servers)]
+		}
+	}
 
-`), 0644)
+	overlay := Overlay
+	if !cmd.Flags().Changed("overlay") && doc != nil {
+		overlay = p.confirm("Write an overlay listing the operations to prune")
+	}
 
+	configFile := filepath.Join(dir, configFileName)
+	err = ioutil.WriteFile(configFile, []byte(configText(specLocation, apiBase, doc)), 0644)
+	if err != nil {
+		log.Fatalf(vebosityFmt, err)
+	}
+
+	// lets editors validate and complete the config file.
+	schema, err := json.MarshalIndent(api.ConfigJSONSchema(), "", "  ")
+	if err != nil {
+		log.Fatalf(vebosityFmt, err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, schemaFileName), append(schema, '\n'), 0644)
 	if err != nil {
-		log.Fatalf("%+v", err)
+		log.Fatalf(vebosityFmt, err)
+	}
+
+	if overlay {
+		err = ioutil.WriteFile(filepath.Join(dir, overlayFileName), overlayText(doc), 0644)
+		if err != nil {
+			log.Fatalf(vebosityFmt, err)
+		}
 	}
 
 	log.Printf(`Project created in the '%s' directory.`, dir)
 	log.Printf(`Edit '%s' and then run:`, configFile)
 	log.Println()
 	log.Println(`    cd`, dir)
+	log.Println(`    graphql-4-apis validate`)
 	log.Println(`    graphql-4-apis serve`)
 	log.Println()
 }
+
+// projectLocation returns the location of the openapi document as seen from the project directory.
+func projectLocation(dir string, specFile string) (string, error) {
+	if u, err := url.Parse(specFile); err == nil && u.Scheme != "" && u.Host != "" {
+		return specFile, nil
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	absSpec, err := filepath.Abs(specFile)
+	if err != nil {
+		return "", err
+	}
+	location, err := filepath.Rel(absDir, absSpec)
+	if err != nil || strings.HasPrefix(location, "..") {
+		return absSpec, nil
+	}
+	return filepath.ToSlash(location), nil
+}
+
+// serverURLs lists the server URLs of the openapi document, with their variables set to their default
+// value.  Relative URLs are resolved against the URL of a remote document.
+func serverURLs(doc *openapi3.T, specFile string) []string {
+	base, err := url.Parse(specFile)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		base = nil
+	}
+	var result []string
+	for _, server := range doc.Servers {
+		if server == nil || server.URL == "" {
+			continue
+		}
+		serverURL, err := apis.ServerURL(server, nil, base)
+		if err != nil {
+			continue
+		}
+		result = append(result, serverURL)
+	}
+	return result
+}
+
+func configText(specLocation string, apiBase string, doc *openapi3.T) string {
+	text := &strings.Builder{}
+	fmt.Fprintf(text, `# yaml-language-server: $schema=%s
+#
+# Configure the host and port the service will listen on
+listen: 0.0.0.0:8080
+
+# Configures how to get the openapi document.  It can be openapi v2 or v3.
+spec:
+  url: %s
+  # Set to true if the http server has an invalid cert that you trust
+  #insecure-client: true
+  # Set if you need to set a bearer token to get the openapi document.
+  #bearer-token: mytoken
+
+# Configures the base URL that API requests will get issued against.
+api:
+`, schemaFileName, yamlString(specLocation))
+	if apiBase != "" {
+		fmt.Fprintf(text, "  url: %s\n", yamlString(apiBase))
+	} else {
+		text.WriteString("  # Defaults to the first server of the openapi document.\n")
+		text.WriteString("  #url: https://api.example.com\n")
+	}
+	text.WriteString("  # Set to true if the http server has an invalid cert that you trust\n")
+	text.WriteString("  #insecure-client: true\n")
+
+	apiKey, bearerToken := false, false
+	for _, name := range securitySchemeNames(doc) {
+		scheme := doc.Components.SecuritySchemes[name].Value
+		switch {
+		case scheme.Type == "apiKey":
+			fmt.Fprintf(text, "  # The %s security scheme sends an API key in the %s %s.\n", name, scheme.Name, scheme.In)
+			apiKey = true
+		case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "bearer"),
+			scheme.Type == "oauth2", scheme.Type == "openIdConnect":
+			fmt.Fprintf(text, "  # The %s security scheme sends a bearer token in the Authorization header.\n", name)
+			bearerToken = true
+		default:
+			fmt.Fprintf(text, "  # The %s security scheme (%s) is not supported.\n", name, strings.TrimSpace(scheme.Type+" "+scheme.Scheme))
+		}
+	}
+	if apiKey {
+		text.WriteString("  # Set the API_KEY environment variable, or replace the reference with the API key.\n")
+		text.WriteString("  api-key: ${API_KEY:-}\n")
+	}
+	if bearerToken {
+		text.WriteString("  # Set the BEARER_TOKEN environment variable, or replace the reference with the token.\n")
+		text.WriteString("  bearer-token: ${BEARER_TOKEN:-}\n")
+	} else {
+		text.WriteString("  # Set if you need to set a bearer token in api requests.\n")
+		text.WriteString("  #bearer-token: mytoken\n")
+	}
+	return text.String()
+}
+
+func securitySchemeNames(doc *openapi3.T) []string {
+	if doc == nil {
+		return nil
+	}
+	var names []string
+	for name, ref := range doc.Components.SecuritySchemes {
+		if ref != nil && ref.Value != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// overlayText returns an OpenAPI Overlay (https://spec.openapis.org/overlay/v1.0.0.html) with an action
+// for every operation of the openapi document.  None of them removes its operation, the user sets remove
+// to true on the ones to prune.
+func overlayText(doc *openapi3.T) []byte {
+	text := &strings.Builder{}
+	text.WriteString(`# Set remove to true on the operations that the GraphQL schema should not expose, then apply
+# the overlay to the openapi document with an OpenAPI Overlay tool.
+overlay: 1.0.0
+info:
+  title: Operations exposed by the GraphQL schema
+  version: 1.0.0
+actions:
+`)
+	var paths []string
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		operations := doc.Paths[path].Operations()
+		var methods []string
+		for method := range operations {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			operation := operations[method]
+			target := fmt.Sprintf("$.paths['%s'].%s", strings.Replace(path, "'", `\'`, -1), strings.ToLower(method))
+			fmt.Fprintf(text, "- target: %s\n", yamlString(target))
+			description := operation.OperationID
+			if operation.Summary != "" {
+				description = strings.TrimPrefix(description+": "+operation.Summary, ": ")
+			}
+			if description != "" {
+				fmt.Fprintf(text, "  description: %s\n", yamlString(description))
+			}
+			text.WriteString("  remove: false\n")
+		}
+	}
+	return []byte(text.String())
+}
+
+// yamlString quotes the values that YAML would not read back as the same string.
+func yamlString(value string) string {
+	var parsed interface{}
+	if err := yaml.Unmarshal([]byte("v: "+value), &parsed); err == nil {
+		if m, ok := parsed.(map[string]interface{}); ok && m["v"] == value {
+			return value
+		}
+	}
+	return strconv.Quote(value)
+}
+
+// prompter asks for the values that were not given as options, when running in a terminal.
+type prompter struct {
+	interactive bool
+	in          *bufio.Reader
+}
+
+func (p *prompter) ask(question string, defaultValue string) string {
+	if !p.interactive {
+		return defaultValue
+	}
+	if defaultValue != "" {
+		fmt.Printf("%s [%s]: ", question, defaultValue)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+	answer, err := p.in.ReadString('\n')
+	if err != nil {
+		// there is no one to answer, use the defaults.
+		fmt.Println()
+		p.interactive = false
+	}
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return defaultValue
+	}
+	return answer
+}
+
+func (p *prompter) choose(question string, choices []string) int {
+	if !p.interactive {
+		return 0
+	}
+	for i, choice := range choices {
+		fmt.Printf("  %d) %s\n", i+1, choice)
+	}
+	for {
+		answer := p.ask(question, "1")
+		if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(choices) {
+			return i - 1
+		}
+		fmt.Printf("Enter a number between 1 and %d.\n", len(choices))
+	}
+}
+
+func (p *prompter) confirm(question string) bool {
+	answer := strings.ToLower(p.ask(question+" (y/n)", "n"))
+	return answer == "y" || answer == "yes"
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
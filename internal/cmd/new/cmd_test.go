@@ -0,0 +1,137 @@
+package new
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	api "github.com/chirino/graphql-4-apis/internal/cmd/serve"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/stretchr/testify/require"
+)
+
+const testSpec = `openapi: 3.0.0
+info:
+  title: Pets
+  version: 0.0.1
+servers:
+  - url: https://{region}.example.com/v1
+    variables:
+      region:
+        default: eu
+  - url: /relative
+security:
+  - key: []
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      summary: List the pets
+      responses:
+        "200":
+          description: OK
+    post:
+      operationId: addPet
+      responses:
+        "200":
+          description: OK
+  /pets/{id}:
+    delete:
+      responses:
+        "204":
+          description: Deleted
+components:
+  securitySchemes:
+    key:
+      type: apiKey
+      name: X-API-Key
+      in: header
+    token:
+      type: http
+      scheme: bearer
+    basic:
+      type: http
+      scheme: basic
+`
+
+func TestNewProject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "new-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	specFile := filepath.Join(dir, "openapi.yaml")
+	require.NoError(t, ioutil.WriteFile(specFile, []byte(testSpec), 0644))
+
+	doc, err := apis.LoadOpenApiV2orV3Doc(apis.EndpointOptions{URL: specFile})
+	require.NoError(t, err)
+
+	// relative servers are only resolved for remote documents.
+	require.Equal(t, []string{"https://eu.example.com/v1"}, serverURLs(doc, specFile))
+	require.Equal(t, []string{"https://eu.example.com/v1", "https://example.com/relative"}, serverURLs(doc, "https://example.com/openapi.yaml"))
+
+	location, err := projectLocation(dir, specFile)
+	require.NoError(t, err)
+	require.Equal(t, "openapi.yaml", location)
+
+	config := configText(location, "https://eu.example.com/v1", doc)
+	require.Equal(t, `# yaml-language-server: $schema=graphql-4-apis.schema.json
+#
+# Configure the host and port the service will listen on
+listen: 0.0.0.0:8080
+
+# Configures how to get the openapi document.  It can be openapi v2 or v3.
+spec:
+  url: openapi.yaml
+  # Set to true if the http server has an invalid cert that you trust
+  #insecure-client: true
+  # Set if you need to set a bearer token to get the openapi document.
+  #bearer-token: mytoken
+
+# Configures the base URL that API requests will get issued against.
+api:
+  url: https://eu.example.com/v1
+  # Set to true if the http server has an invalid cert that you trust
+  #insecure-client: true
+  # The basic security scheme (http basic) is not supported.
+  # The key security scheme sends an API key in the X-API-Key header.
+  # The token security scheme sends a bearer token in the Authorization header.
+  # Set the API_KEY environment variable, or replace the reference with the API key.
+  api-key: ${API_KEY:-}
+  # Set the BEARER_TOKEN environment variable, or replace the reference with the token.
+  bearer-token: ${BEARER_TOKEN:-}
+`, config)
+
+	// the new project validates without setting the credentials.
+	configFile := filepath.Join(dir, configFileName)
+	require.NoError(t, ioutil.WriteFile(configFile, []byte(config), 0644))
+	os.Unsetenv("API_KEY")
+	os.Unsetenv("BEARER_TOKEN")
+	loaded, err := api.LoadConfig(configFile)
+	require.NoError(t, err)
+	require.Equal(t, "https://eu.example.com/v1", loaded.APIBase.URL)
+	require.Equal(t, "", loaded.APIBase.ApiKey)
+
+	require.Equal(t, `# Set remove to true on the operations that the GraphQL schema should not expose, then apply
+# the overlay to the openapi document with an OpenAPI Overlay tool.
+overlay: 1.0.0
+info:
+  title: Operations exposed by the GraphQL schema
+  version: 1.0.0
+actions:
+- target: $.paths['/pets'].get
+  description: "listPets: List the pets"
+  remove: false
+- target: $.paths['/pets'].post
+  description: addPet
+  remove: false
+- target: $.paths['/pets/{id}'].delete
+  remove: false
+`, string(overlayText(doc)))
+}
+
+func TestNewProjectWithoutDocument(t *testing.T) {
+	config := configText("openapi.json", "", nil)
+	require.Contains(t, config, "  #url: https://api.example.com\n")
+	require.Contains(t, config, "  #bearer-token: mytoken\n")
+	require.NotContains(t, config, "api-key")
+}
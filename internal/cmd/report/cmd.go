@@ -0,0 +1,91 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/chirino/graphql-4-apis/internal/cmd/root"
+	api "github.com/chirino/graphql-4-apis/internal/cmd/serve"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/spf13/cobra"
+)
+
+var (
+	Command = &cobra.Command{
+		Use:   "report",
+		Short: "Reports the parts of the openapi document that could not be mapped",
+		Long: `Reports the diagnostics collected while generating the GraphQL schema, without accessing the API.
+Each entry has a severity, the JSON pointer of the openapi element, the GraphQL path and a reason.
+The command exits with status 1 when an entry is at least as severe as the --fail-on level, which
+makes it usable as a CI check.`,
+		Run: run,
+	}
+	ConfigFile = ""
+	SpecFile   = ""
+	Format     = "table"
+	FailOn     = "error"
+)
+
+func init() {
+	Command.Flags().StringVar(&ConfigFile, "config", "graphql-4-apis.yaml", "path to the config file to load")
+	Command.Flags().StringVar(&SpecFile, "spec", "", "path or URL of the openapi document to use instead of the one in the config file")
+	Command.Flags().StringVar(&Format, "format", "table", "output format: table or json")
+	Command.Flags().StringVar(&FailOn, "fail-on", "error", "exit with status 1 on diagnostics of this severity or higher: info, warning, error or none")
+	root.Command.AddCommand(Command)
+}
+
+func run(_ *cobra.Command, _ []string) {
+	vebosityFmt := "%v"
+	if root.Verbose {
+		vebosityFmt = "%+v\n"
+	}
+
+	if Format != "table" && Format != "json" {
+		log.Fatalf("invalid format: %s", Format)
+	}
+	threshold := apis.Severity(-1)
+	if FailOn != "none" {
+		var err error
+		threshold, err = apis.ParseSeverity(FailOn)
+		if err != nil {
+			log.Fatalf(vebosityFmt, err)
+		}
+	}
+
+	config, err := api.LoadSpecConfig(ConfigFile, SpecFile)
+	if err != nil {
+		log.Fatalf(vebosityFmt, err)
+	}
+	if config.Webhooks.Enabled {
+		config.WebhookReceiver = apis.NewWebhookReceiver()
+	}
+	diagnostics := &apis.Diagnostics{Entries: []apis.Diagnostic{}}
+	config.Diagnostics = diagnostics
+
+	_, err = apis.CreateGatewayEngine(config.Config)
+	if err != nil {
+		log.Fatalf(vebosityFmt, err)
+	}
+
+	if Format == "json" {
+		data, err := json.MarshalIndent(diagnostics, "", "  ")
+		if err != nil {
+			log.Fatalf(vebosityFmt, err)
+		}
+		fmt.Println(string(data))
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "SEVERITY\tPOINTER\tPATH\tREASON")
+		for _, entry := range diagnostics.Entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Severity, entry.Pointer, entry.Path, entry.Reason)
+		}
+		w.Flush()
+	}
+
+	if FailOn != "none" && diagnostics.Count(threshold) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,81 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/internal/cmd/root"
+	api "github.com/chirino/graphql-4-apis/internal/cmd/serve"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/spf13/cobra"
+)
+
+var (
+	Command = &cobra.Command{
+		Use:   "schema",
+		Short: "Prints the GraphQL schema of the gateway service",
+		Long: `Prints the GraphQL schema the gateway service would serve, without accessing the API.
+The openapi document is loaded from the config file, or from the --spec file or URL.`,
+		Run: run,
+	}
+	ConfigFile    = ""
+	SpecFile      = ""
+	Introspection = false
+	OutputFile    = ""
+)
+
+func init() {
+	Command.Flags().StringVar(&ConfigFile, "config", "graphql-4-apis.yaml", "path to the config file to load")
+	Command.Flags().StringVar(&SpecFile, "spec", "", "path or URL of the openapi document to use instead of the one in the config file")
+	Command.Flags().BoolVar(&Introspection, "introspection", false, "print the introspection query result as JSON instead of the SDL")
+	Command.Flags().StringVarP(&OutputFile, "output", "o", "", "write the schema to this file instead of stdout")
+	root.Command.AddCommand(Command)
+}
+
+func run(_ *cobra.Command, _ []string) {
+	vebosityFmt := "%v"
+	if root.Verbose {
+		vebosityFmt = "%+v\n"
+	}
+
+	config, err := api.LoadSpecConfig(ConfigFile, SpecFile)
+	if err != nil {
+		log.Fatalf(vebosityFmt, err)
+	}
+	// map the webhook subscriptions like the serve command does.
+	if config.Webhooks.Enabled {
+		config.WebhookReceiver = apis.NewWebhookReceiver()
+	}
+
+	engine, err := apis.CreateGatewayEngine(config.Config)
+	if err != nil {
+		log.Fatalf(vebosityFmt, err)
+	}
+
+	output := []byte(engine.Schema.String())
+	if Introspection {
+		data, err := graphql.GetSchemaIntrospectionJSON(engine.ServeGraphQL)
+		if err != nil {
+			log.Fatalf(vebosityFmt, err)
+		}
+		buf := bytes.Buffer{}
+		if err := json.Indent(&buf, data, "", "  "); err != nil {
+			log.Fatalf(vebosityFmt, err)
+		}
+		buf.WriteString("\n")
+		output = buf.Bytes()
+	}
+
+	if OutputFile != "" {
+		err = ioutil.WriteFile(OutputFile, output, 0644)
+		if err != nil {
+			log.Fatalf(vebosityFmt, err)
+		}
+		return
+	}
+	fmt.Print(string(output))
+}
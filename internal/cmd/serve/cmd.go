@@ -1,17 +1,25 @@
 package api
 
 import (
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/chirino/graphql"
 	"github.com/chirino/graphql-4-apis/internal/cmd/root"
 	"github.com/chirino/graphql-4-apis/pkg/apis"
 	"github.com/chirino/graphql/graphiql"
 	"github.com/chirino/graphql/httpgql"
+	"github.com/chirino/graphql/schema"
 	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -19,45 +27,176 @@ var (
 	Command = &cobra.Command{
 		Use:   "serve",
 		Short: "Runs the gateway service",
-		Run:   run,
+		Long: `Runs the gateway service.  The service is reloaded when the config file or a local openapi
+document changes, when a remote openapi document fetched every --reload-interval changes, or when
+the process receives a SIGHUP signal.  The previous schema is kept if the reload fails.`,
+		Run: run,
 	}
 	ConfigFile = ""
+	// Settings are name=value config overrides, like api.bearer-token=${TOKEN}.
+	Settings  []string
+	Mock      = false
+	RecordDir = ""
+	ReplayDir = ""
+	// ReloadInterval is how often a remote openapi document is fetched to check for changes.
+	ReloadInterval time.Duration
 )
 
 func init() {
 	Command.Flags().StringVar(&ConfigFile, "config", "graphql-4-apis.yaml", "path to the config file to load")
+	Command.Flags().StringArrayVar(&Settings, "set", nil, "override a config setting, like --set api.bearer-token=${TOKEN}, can be repeated")
+	Command.Flags().BoolVar(&Mock, "mock", false, "serve data built from the openapi examples and schemas instead of calling the API")
+	Command.Flags().StringVar(&RecordDir, "record", "", "save the API requests and responses as cassette files in this directory")
+	Command.Flags().StringVar(&ReplayDir, "replay", "", "serve the API responses recorded in this directory instead of calling the API")
+	Command.Flags().DurationVar(&ReloadInterval, "reload-interval", 0, "how often to fetch a remote openapi document to reload it when it changes, it is only fetched on SIGHUP if 0.  Files of external $refs are only reloaded on SIGHUP")
 	root.Command.AddCommand(Command)
 }
 
 type Config struct {
-	Listen string `json:"listen"`
+	// Listen is the host and port the service will listen on.
+	Listen string `yaml:"listen,omitempty" json:"listen,omitempty"`
 	apis.Config
 }
 
+// LoadConfig loads the gateway configuration file.  ${VAR} and file: references are expanded in the
+// config values, and the GRAPHQL4APIS_* environment variables and the Settings override them.  Unknown
+// settings and values of the wrong type are reported with their line number.
+func LoadConfig(configFile string) (Config, error) {
+	config := Config{}
+	file, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return config, err
+	}
+
+	doc := map[string]interface{}{}
+	err = yaml.Unmarshal(file, &doc)
+	if err != nil {
+		return config, err
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+	err = applyOverrides(doc, Settings)
+	if err != nil {
+		return config, err
+	}
+	_, err = interpolate(doc)
+	if err != nil {
+		return config, err
+	}
+	if problems := checkConfig(doc, string(file)); len(problems) > 0 {
+		return config, errors.Errorf("invalid config file %s:\n  %s", configFile, strings.Join(problems, "\n  "))
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return config, err
+	}
+	setDefaults(&config)
+	return config, nil
+}
+
+func setDefaults(config *Config) {
+	if config.QueryType == "" {
+		config.QueryType = `QueryApi`
+	}
+	if config.MutationType == "" {
+		config.MutationType = `MutationApi`
+	}
+	if config.SubscriptionType == "" {
+		config.SubscriptionType = `SubscriptionApi`
+	}
+	if !root.Verbose {
+		config.Log = log.New(ioutil.Discard, "", 0)
+	}
+}
+
+// LoadSpecConfig loads the gateway configuration for commands that generate the schema without
+// accessing the API.  The specFile, when set, replaces the openapi document of the config file, and
+// the config file is optional in that case.
+func LoadSpecConfig(configFile string, specFile string) (Config, error) {
+	config := Config{}
+	if _, err := os.Stat(configFile); specFile == "" || err == nil {
+		config, err = LoadConfig(configFile)
+		if err != nil {
+			return config, err
+		}
+	} else {
+		setDefaults(&config)
+	}
+	if specFile != "" {
+		config.Openapi = apis.EndpointOptions{URL: specFile}
+	}
+	if config.APIBase.URL == "" {
+		// the API is never called, but the engine needs a base URL when the openapi
+		// document does not list any servers.
+		config.APIBase.URL = "http://localhost"
+	}
+	return config, nil
+}
+
 func run(_ *cobra.Command, _ []string) {
 	vebosityFmt := "%v"
 	if !root.Verbose {
 		vebosityFmt = "%+v\n"
 	}
 
-	file, err := ioutil.ReadFile(ConfigFile)
+	config, err := LoadConfig(ConfigFile)
 	if err != nil {
 		log.Fatalf("%+v", err)
 	}
 
-	config := Config{}
-	err = yaml.Unmarshal(file, &config)
-	if err != nil {
-		log.Fatalf("%+v", err)
+	var client *http.Client
+	switch {
+	case RecordDir != "" && ReplayDir != "":
+		log.Fatalf("the --record and --replay options can't be used together")
+	case RecordDir != "":
+		recorder, err := apis.NewRecordingTransport(RecordDir, &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: config.APIBase.InsecureClient},
+		})
+		if err != nil {
+			log.Fatalf(vebosityFmt, err)
+		}
+		client = &http.Client{Transport: recorder}
+	case ReplayDir != "":
+		replay, err := apis.NewReplayTransport(ReplayDir)
+		if err != nil {
+			log.Fatalf(vebosityFmt, err)
+		}
+		client = &http.Client{Transport: replay}
 	}
-	config.QueryType = `QueryApi`
-	config.MutationType = `MutationApi`
 
-	if !root.Verbose {
-		config.Log = log.New(ioutil.Discard, "", 0)
+	// the receiver is shared by the reloaded engines so that webhook subscriptions keep their events.
+	receiver := apis.NewWebhookReceiver()
+	engine := &engineHolder{}
+	reloader := &reloader{
+		configFile:     ConfigFile,
+		remoteInterval: ReloadInterval,
+		engine:         engine,
+		build: func(c Config) (*graphql.Engine, error) {
+			if Mock {
+				c.Mock.Enabled = true
+			}
+			if client != nil {
+				c.APIBase.Client = client
+			}
+			if c.Webhooks.Enabled != config.Webhooks.Enabled {
+				// the receiver is only mounted when the server starts.
+				return nil, errors.New("webhooks.enabled can only be changed by restarting the server")
+			}
+			if c.Webhooks.Enabled {
+				c.WebhookReceiver = receiver
+			}
+			return apis.CreateGatewayEngine(c.Config)
+		},
 	}
-
-	engine, err := apis.CreateGatewayEngine(config.Config)
+	if _, err := reloader.load(true, true); err != nil {
+		log.Fatalf(vebosityFmt, err)
+	}
+	go reloader.watch()
 
 	if config.Listen == "" {
 		config.Listen = "0.0.0.0:8080"
@@ -69,9 +208,21 @@ func run(_ *cobra.Command, _ []string) {
 	}
 
 	endpoint := fmt.Sprintf("http://%s:%s", host, port)
-	http.Handle("/graphql", &httpgql.Handler{ServeGraphQLStream: engine.ServeGraphQLStream})
+	http.Handle("/graphql", &apis.MultipartHandler{
+		Next:               &httpgql.Handler{ServeGraphQLStream: engine.ServeGraphQLStream},
+		ServeGraphQLStream: engine.ServeGraphQLStream,
+	})
 	log.Printf("GraphQL endpoint running at %s/graphql", endpoint)
-	http.Handle("/", graphiql.New(endpoint+"/graphql", false))
+	if config.Webhooks.Enabled {
+		http.Handle("/webhooks/", http.StripPrefix("/webhooks", receiver))
+		log.Printf("Webhook receiver running at %s/webhooks", endpoint)
+	}
+	if engine.get().Schema.EntryPoints[schema.Subscription] != nil {
+		// subscriptions need the UI to use the websocket transport.
+		http.Handle("/", graphiql.New(fmt.Sprintf("ws://%s:%s/graphql", host, port), true))
+	} else {
+		http.Handle("/", graphiql.New(endpoint+"/graphql", false))
+	}
 	log.Printf("GraphQL UI running at %s", endpoint)
 
 	log.Fatalf(vebosityFmt, http.ListenAndServe(config.Listen, nil))
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// EnvPrefix is the prefix of the environment variables that override config settings, like
+// GRAPHQL4APIS_API_BEARER_TOKEN for the api.bearer-token setting.
+const EnvPrefix = "GRAPHQL4APIS_"
+
+// filePrefix marks the config values that are read from a file, like `file:/run/secrets/token`.
+const filePrefix = "file:"
+
+// interpolate expands the ${VAR} and ${VAR:-default} references and the file: values of the strings of a
+// decoded config document.  The expanded values of boolean and number settings are parsed, like the
+// overrides are.
+func interpolate(value interface{}) (interface{}, error) {
+	kinds := map[string]reflect.Kind{}
+	for _, s := range settings(reflect.TypeOf(Config{}), nil) {
+		kinds[strings.ToLower(s.name())] = s.kind
+	}
+	return interpolateValue(value, nil, kinds)
+}
+
+func interpolateValue(value interface{}, path []string, kinds map[string]reflect.Kind) (interface{}, error) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for k, v := range value {
+			expanded, err := interpolateValue(v, append(append([]string{}, path...), k), kinds)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s", k)
+			}
+			value[k] = expanded
+		}
+	case []interface{}:
+		for i, v := range value {
+			expanded, err := interpolateValue(v, path, kinds)
+			if err != nil {
+				return nil, err
+			}
+			value[i] = expanded
+		}
+	case string:
+		expanded, err := interpolateString(value)
+		if err != nil {
+			return nil, err
+		}
+		if kind, ok := kinds[strings.ToLower(strings.Join(path, "."))]; ok && expanded != value {
+			return parseScalar(kind, expanded)
+		}
+		return expanded, nil
+	}
+	return value, nil
+}
+
+// parseScalar parses the booleans and numbers of the settings that are not strings.
+func parseScalar(kind reflect.Kind, value string) (interface{}, error) {
+	if kind == reflect.String {
+		return value, nil
+	}
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(value), &v); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return v, nil
+}
+
+func interpolateString(value string) (string, error) {
+	if strings.HasPrefix(value, filePrefix) {
+		data, err := ioutil.ReadFile(strings.TrimPrefix(value, filePrefix))
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		// secret files usually end with a new line that is not part of the secret.
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	result := strings.Builder{}
+	for {
+		i := strings.Index(value, "$")
+		if i < 0 || i == len(value)-1 {
+			result.WriteString(value)
+			return result.String(), nil
+		}
+		result.WriteString(value[:i])
+		value = value[i:]
+		switch value[1] {
+		case '$':
+			// $$ escapes a $.
+			result.WriteString("$")
+			value = value[2:]
+		case '{':
+			end := strings.Index(value, "}")
+			if end < 0 {
+				return "", errors.Errorf("unterminated variable reference: %s", value)
+			}
+			name, defaultValue, hasDefault := value[2:end], "", false
+			if j := strings.Index(name, ":-"); j >= 0 {
+				name, defaultValue, hasDefault = name[:j], name[j+2:], true
+			}
+			v, found := os.LookupEnv(name)
+			switch {
+			case found && (v != "" || !hasDefault):
+				result.WriteString(v)
+			case hasDefault:
+				result.WriteString(defaultValue)
+			default:
+				return "", errors.Errorf("environment variable is not set: %s", name)
+			}
+			value = value[end+1:]
+		default:
+			result.WriteString("$")
+			value = value[1:]
+		}
+	}
+}
+
+// setting is a config value that can be overridden.
+type setting struct {
+	path []string
+	kind reflect.Kind
+}
+
+func (s setting) name() string {
+	return strings.Join(s.path, ".")
+}
+
+func (s setting) envName() string {
+	name := strings.ToUpper(strings.Join(s.path, "_"))
+	return EnvPrefix + strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
+
+// settings lists the scalar settings of a config type, by their yaml path.
+func settings(t reflect.Type, prefix []string) []setting {
+	var result []setting
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || field.PkgPath != "" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			result = append(result, settings(field.Type, prefix)...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		path := append(append([]string{}, prefix...), name)
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			result = append(result, settings(field.Type, path)...)
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			result = append(result, setting{path: path, kind: field.Type.Kind()})
+		}
+	}
+	return result
+}
+
+// applyOverrides sets the settings overridden by GRAPHQL4APIS_* environment variables, then the
+// ones overridden by the name=value overrides.
+func applyOverrides(doc map[string]interface{}, overrides []string) error {
+	all := settings(reflect.TypeOf(Config{}), nil)
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].name() < all[j].name()
+	})
+	byName := map[string]setting{}
+	for _, s := range all {
+		byName[strings.ToLower(s.name())] = s
+		if value, found := os.LookupEnv(s.envName()); found {
+			if err := set(doc, s, value); err != nil {
+				return errors.Wrapf(err, "invalid %s environment variable", s.envName())
+			}
+		}
+	}
+	for _, override := range overrides {
+		kv := strings.SplitN(override, "=", 2)
+		if len(kv) != 2 {
+			return errors.Errorf("invalid setting, expected name=value: %s", override)
+		}
+		s, ok := byName[strings.ToLower(kv[0])]
+		if !ok {
+			return errors.Errorf("unknown setting: %s", kv[0])
+		}
+		if err := set(doc, s, kv[1]); err != nil {
+			return errors.Wrapf(err, "invalid %s setting", kv[0])
+		}
+	}
+	return nil
+}
+
+func set(doc map[string]interface{}, s setting, value string) error {
+	// strings are expanded with the rest of the config.
+	var v interface{} = value
+	if s.kind != reflect.String {
+		expanded, err := interpolateString(value)
+		if err != nil {
+			return err
+		}
+		v, err = parseScalar(s.kind, expanded)
+		if err != nil {
+			return err
+		}
+	}
+	for _, key := range s.path[:len(s.path)-1] {
+		child, ok := lookup(doc, key).(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+		}
+		doc[key] = child
+		doc = child
+	}
+	key := s.path[len(s.path)-1]
+	lookup(doc, key)
+	doc[key] = v
+	return nil
+}
+
+// lookup returns the value of a key, keys are matched case insensitively like the config decoder does.
+// A key matching with a different case is renamed to the given one.
+func lookup(doc map[string]interface{}, key string) interface{} {
+	if v, ok := doc[key]; ok {
+		return v
+	}
+	for k, v := range doc {
+		if strings.EqualFold(k, key) {
+			delete(doc, k)
+			doc[key] = v
+			return v
+		}
+	}
+	return nil
+}
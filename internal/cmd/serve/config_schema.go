@@ -0,0 +1,308 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+)
+
+// settingDescriptions documents the config settings in the JSON Schema, by their path.
+var settingDescriptions = map[string]string{
+	"listen":                    "The host and port the service will listen on, like 0.0.0.0:8080.",
+	"spec":                      "Configures how to get the openapi document.  It can be openapi v2 or v3.",
+	"api":                       "Configures the base URL that API requests will get issued against.  Defaults to the server of the openapi document selected by the server setting.",
+	"url":                       "The URL of the endpoint.",
+	"spec.url":                  "The URL or the local file path of the openapi document.",
+	"bearer-token":              "The Authentication Bearer token added to the request headers.",
+	"api-key":                   "The API key of the endpoint.",
+	"insecure-client":           "Allows connecting to TLS servers that do not have a valid certificate.",
+	"server":                    "Selects the server of the openapi document used when the api url is not configured.",
+	"server.index":              "Selects the server by its position in the servers of the openapi document that have a URL, the first one is 0.",
+	"server.description":        "Selects the server with this description instead, it is matched case insensitively.",
+	"server.variables":          "Overrides the default values of the server variables, like region: eu.",
+	"query-type":                "The name of the GraphQL type holding the query fields.",
+	"mutation-type":             "The name of the GraphQL type holding the mutation fields.",
+	"subscription-type":         "The name of the GraphQL type holding the subscription fields.",
+	"xml-responses":             "Decodes XML responses into the types generated from their schema, otherwise they are returned as a String.",
+	"binary-responses":          "How binary responses are returned: base64 (the default) returns the data, url returns the URL the data can be downloaded from when the operation needs no credentials.",
+	"validate-inputs":           "Checks the arguments against the constraints of the openapi schemas before calling the API.",
+	"required-results":          "Makes the result fields of the required and not nullable properties non-null.",
+	"field-naming":              "The naming convention of the generated fields and arguments.",
+	"namespaces":                "Groups the query fields, and optionally the mutation fields, by their openapi tags.",
+	"namespaces.enabled":        "Groups the operations by their first tag.",
+	"namespaces.mutations":      "Also groups the mutation fields, the mutations selected in the same namespace then run in parallel instead of serially.",
+	"namespaces.operations":     "Maps operation ids to the namespace to group them in, overriding their tags.",
+	"streaming":                 "Configures the operations that stream their responses, those are exposed as subscriptions.",
+	"streaming.operations":      "Maps the ids of operations that stream their response to the format of the stream.",
+	"polling":                   "Exposes GET operations as subscriptions that poll the API for changes.",
+	"polling.interval":          "The default interval between polls, like 30s.",
+	"polling.operations":        "Maps the ids of the operations to poll to their poll interval, an empty interval uses the default one.",
+	"webhooks":                  "Receives the callback and webhook requests the API sends, and exposes them as subscriptions.",
+	"webhooks.enabled":          "Exposes the callbacks and webhooks as subscriptions and receives their requests at /webhooks.  Changing it needs a restart.",
+	"webhooks.secret":           "Authenticates the webhook requests.  The API sends it in the X-Webhook-Secret header, or signs the payloads with it.",
+	"webhooks.signature-header": "The header holding the hex encoded HMAC-SHA256 signature of the payloads, defaults to X-Webhook-Signature.",
+	"mock":                      "Serves responses built from the openapi document examples and schemas instead of calling the API.",
+	"mock.enabled":              "Turns on the mock mode.",
+	"mock.seed":                 "Changes the generated data.  A request always gets the same data for a given seed.",
+}
+
+// settingEnums lists the values allowed by the settings that only accept a few values.
+var settingEnums = map[string][]string{
+	"binary-responses":     {apis.BinaryResponsesBase64, apis.BinaryResponsesURL},
+	"field-naming":         {apis.NamingAsIs, apis.NamingCamelCase, apis.NamingPascalCase},
+	"streaming.operations": {apis.StreamFormatNDJSON, apis.StreamFormatSSE},
+}
+
+// legacyKeys maps the keys of older config files to the current ones.
+var legacyKeys = map[string]string{
+	"openapi":        "spec",
+	"apibase":        "api",
+	"insecureclient": "insecure-client",
+	"bearertoken":    "bearer-token",
+	"apikey":         "api-key",
+}
+
+// ConfigJSONSchema returns the JSON Schema of the config file, for editors to validate and complete it.
+func ConfigJSONSchema() map[string]interface{} {
+	s := typeSchema(reflect.TypeOf(Config{}), "")
+	s["$schema"] = "http://json-schema.org/draft-07/schema#"
+	s["title"] = "graphql-4-apis config"
+	return s
+}
+
+func typeSchema(t reflect.Type, path string) map[string]interface{} {
+	result := map[string]interface{}{}
+	description := settingDescriptions[path]
+	if description == "" {
+		description = settingDescriptions[path[strings.LastIndex(path, ".")+1:]]
+	}
+	if description != "" {
+		result["description"] = description
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		for _, f := range configFields(t) {
+			properties[f.name] = typeSchema(f.Type, joinPath(path, f.name))
+		}
+		result["type"] = "object"
+		result["properties"] = properties
+		result["additionalProperties"] = false
+	case reflect.Map:
+		values := typeSchema(t.Elem(), "")
+		if enum, ok := settingEnums[path]; ok {
+			values["enum"] = enumValues(enum)
+		}
+		result["type"] = "object"
+		result["additionalProperties"] = values
+	case reflect.Bool:
+		result["type"] = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		result["type"] = "integer"
+	case reflect.Float32, reflect.Float64:
+		result["type"] = "number"
+	default:
+		result["type"] = "string"
+		if enum, ok := settingEnums[path]; ok {
+			result["enum"] = enumValues(enum)
+		}
+	}
+	return result
+}
+
+// enumValues lists the values of an enum setting, an empty value selects the default.
+func enumValues(values []string) []interface{} {
+	result := []interface{}{""}
+	for _, v := range values {
+		result = append(result, v)
+	}
+	return result
+}
+
+// configField is a field of a config struct that can be set in the config file.
+type configField struct {
+	reflect.StructField
+	name string
+}
+
+// configFields lists the fields of a config struct, including the ones of its embedded structs.
+func configFields(t reflect.Type) []configField {
+	var result []configField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || field.PkgPath != "" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			result = append(result, configFields(field.Type)...)
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.Ptr, reflect.Func, reflect.Interface, reflect.Chan, reflect.Slice:
+			// runtime only settings.
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		result = append(result, configField{StructField: field, name: name})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].name < result[j].name
+	})
+	return result
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// checkConfig reports the keys that are not settings and the values that don't have the type of their
+// setting.  The source is used to add the line numbers to the problems.
+func checkConfig(doc map[string]interface{}, source string) []string {
+	var problems []string
+	report := func(path []string, format string, v ...interface{}) {
+		message := fmt.Sprintf("%s: %s", strings.Join(path, "."), fmt.Sprintf(format, v...))
+		if line := findLine(source, path); line > 0 {
+			message = fmt.Sprintf("line %d: %s", line, message)
+		}
+		problems = append(problems, message)
+	}
+
+	var check func(value interface{}, t reflect.Type, path []string)
+	check = func(value interface{}, t reflect.Type, path []string) {
+		if value == nil {
+			return
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				report(path, "expected a map, got: %v", value)
+				return
+			}
+			fields := map[string]configField{}
+			for _, f := range configFields(t) {
+				fields[f.name] = f
+			}
+			for _, key := range sortedKeys(object) {
+				keyPath := append(append([]string{}, path...), key)
+				f, ok := fields[key]
+				if !ok {
+					report(keyPath, "unknown setting%s", suggestion(key, fields))
+					continue
+				}
+				check(object[key], f.Type, keyPath)
+			}
+		case reflect.Map:
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				report(path, "expected a map, got: %v", value)
+				return
+			}
+			enum := settingEnums[strings.Join(path, ".")]
+			for _, key := range sortedKeys(object) {
+				keyPath := append(append([]string{}, path...), key)
+				check(object[key], t.Elem(), keyPath)
+				if v, ok := object[key].(string); ok && enum != nil && v != "" && !contains(enum, v) {
+					report(keyPath, "expected one of %s, got: %s", strings.Join(enum, ", "), v)
+				}
+			}
+		case reflect.Bool:
+			if _, ok := value.(bool); !ok {
+				report(path, "expected true or false, got: %v", value)
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if v, ok := value.(float64); !ok || v != float64(int64(v)) {
+				report(path, "expected an integer, got: %v", value)
+			}
+		case reflect.Float32, reflect.Float64:
+			if _, ok := value.(float64); !ok {
+				report(path, "expected a number, got: %v", value)
+			}
+		case reflect.String:
+			v, ok := value.(string)
+			if !ok {
+				report(path, "expected a string, got: %v", value)
+				return
+			}
+			if enum, ok := settingEnums[strings.Join(path, ".")]; ok && v != "" && !contains(enum, v) {
+				report(path, "expected one of %s, got: %s", strings.Join(enum, ", "), v)
+			}
+		}
+	}
+	check(doc, reflect.TypeOf(Config{}), nil)
+	return problems
+}
+
+func suggestion(key string, fields map[string]configField) string {
+	if name, ok := legacyKeys[strings.ToLower(key)]; ok {
+		if _, ok := fields[name]; ok {
+			return ", use: " + name
+		}
+	}
+	for name := range fields {
+		if strings.EqualFold(name, key) || strings.EqualFold(strings.Replace(name, "-", "", -1), key) {
+			return ", use: " + name
+		}
+	}
+	return ""
+}
+
+// findLine returns the line number of the key at the given path in a YAML document, or 0 if it's not found.
+func findLine(source string, path []string) int {
+	lines := strings.Split(source, "\n")
+	line, indent := -1, -1
+	for _, key := range path {
+		found := false
+		for line++; line < len(lines); line++ {
+			text := lines[line]
+			trimmed := strings.TrimLeft(text, " ")
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			lineIndent := len(text) - len(trimmed)
+			if lineIndent <= indent {
+				// left the parent map.
+				return 0
+			}
+			trimmed = strings.Trim(strings.SplitN(trimmed, ":", 2)[0], `"' `)
+			if trimmed == key {
+				found = true
+				indent = lineIndent
+				break
+			}
+		}
+		if !found {
+			return 0
+		}
+	}
+	return line + 1
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
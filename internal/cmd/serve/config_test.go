@@ -0,0 +1,160 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// setEnv sets an environment variable, the returned function restores it.
+func setEnv(t *testing.T, name string, value string) func() {
+	previous, found := os.LookupEnv(name)
+	require.NoError(t, os.Setenv(name, value))
+	return func() {
+		if found {
+			os.Setenv(name, previous)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func TestInterpolateString(t *testing.T) {
+	defer setEnv(t, "CONFIG_TEST_TOKEN", "s3cret")()
+	defer setEnv(t, "CONFIG_TEST_EMPTY", "")()
+	dir, err := ioutil.TempDir("", "config-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	secretFile := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(secretFile, []byte("from-file\n"), 0600))
+
+	for value, expected := range map[string]string{
+		"plain":                           "plain",
+		"${CONFIG_TEST_TOKEN}":            "s3cret",
+		"Bearer ${CONFIG_TEST_TOKEN}!":    "Bearer s3cret!",
+		"${CONFIG_TEST_MISSING:-default}": "default",
+		"${CONFIG_TEST_EMPTY:-default}":   "default",
+		"${CONFIG_TEST_EMPTY}":            "",
+		"$$${CONFIG_TEST_TOKEN}":          "$s3cret",
+		"a $ b$":                          "a $ b$",
+		"file:" + secretFile:              "from-file",
+	} {
+		actual, err := interpolateString(value)
+		require.NoError(t, err, value)
+		require.Equal(t, expected, actual, value)
+	}
+
+	_, err = interpolateString("${CONFIG_TEST_MISSING}")
+	require.EqualError(t, err, "environment variable is not set: CONFIG_TEST_MISSING")
+	_, err = interpolateString("${CONFIG_TEST_TOKEN")
+	require.Error(t, err)
+}
+
+func TestInterpolateParsesScalarSettings(t *testing.T) {
+	defer setEnv(t, "CONFIG_TEST_MOCK", "true")()
+	defer setEnv(t, "CONFIG_TEST_SEED", "42")()
+	defer setEnv(t, "CONFIG_TEST_PORT", "8080")()
+
+	doc := map[string]interface{}{
+		"listen": "0.0.0.0:${CONFIG_TEST_PORT}",
+		"mock": map[string]interface{}{
+			"enabled": "${CONFIG_TEST_MOCK}",
+			"seed":    "${CONFIG_TEST_SEED}",
+		},
+		"Validate-Inputs": "${CONFIG_TEST_MISSING:-false}",
+		"api": map[string]interface{}{
+			"api-key": "${CONFIG_TEST_SEED}",
+		},
+		"server": map[string]interface{}{
+			"variables": map[string]interface{}{
+				"port": "${CONFIG_TEST_PORT}",
+			},
+		},
+	}
+	_, err := interpolate(doc)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"listen": "0.0.0.0:8080",
+		"mock": map[string]interface{}{
+			"enabled": true,
+			"seed":    float64(42),
+		},
+		"Validate-Inputs": false,
+		"api": map[string]interface{}{
+			"api-key": "42",
+		},
+		"server": map[string]interface{}{
+			"variables": map[string]interface{}{
+				"port": "8080",
+			},
+		},
+	}, doc)
+}
+
+func TestApplyOverrides(t *testing.T) {
+	defer setEnv(t, "GRAPHQL4APIS_API_BEARER_TOKEN", "${CONFIG_TEST_TOKEN}")()
+	defer setEnv(t, "GRAPHQL4APIS_MOCK_ENABLED", "true")()
+
+	doc := map[string]interface{}{
+		"API": map[string]interface{}{
+			"url": "http://localhost",
+		},
+	}
+	err := applyOverrides(doc, []string{"mock.seed=7", "api.URL=http://example.com", "required-results=true"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"api": map[string]interface{}{
+			"url":          "http://example.com",
+			"bearer-token": "${CONFIG_TEST_TOKEN}",
+		},
+		"mock": map[string]interface{}{
+			"enabled": true,
+			"seed":    float64(7),
+		},
+		"required-results": true,
+	}, doc)
+
+	require.EqualError(t, applyOverrides(doc, []string{"api.unknown=1"}), "unknown setting: api.unknown")
+	require.EqualError(t, applyOverrides(doc, []string{"mock.enabled"}), "invalid setting, expected name=value: mock.enabled")
+}
+
+func TestLoadConfig(t *testing.T) {
+	defer setEnv(t, "CONFIG_TEST_TOKEN", "s3cret")()
+	defer setEnv(t, "CONFIG_TEST_MOCK", "true")()
+	dir, err := ioutil.TempDir("", "config-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "graphql-4-apis.yaml")
+	require.NoError(t, ioutil.WriteFile(configFile, []byte(`
+spec:
+  url: openapi.json
+api:
+  url: http://localhost
+  bearer-token: ${CONFIG_TEST_TOKEN}
+mock:
+  enabled: ${CONFIG_TEST_MOCK}
+  seed: ${CONFIG_TEST_SEED:-3}
+`), 0644))
+	config, err := LoadConfig(configFile)
+	require.NoError(t, err)
+	require.Equal(t, "s3cret", config.APIBase.BearerToken)
+	require.True(t, config.Mock.Enabled)
+	require.Equal(t, int64(3), config.Mock.Seed)
+	require.Equal(t, "QueryApi", config.QueryType)
+
+	require.NoError(t, ioutil.WriteFile(configFile, []byte(`
+api:
+  url: http://localhost
+  bearertoken: ${CONFIG_TEST_TOKEN}
+mock:
+  enabled: yes please
+`), 0644))
+	_, err = LoadConfig(configFile)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "line 4: api.bearertoken: unknown setting, use: bearer-token")
+	require.Contains(t, err.Error(), "line 6: mock.enabled: expected true or false, got: yes please")
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+)
+
+// watchInterval is how often the config file and local openapi documents are checked for changes.
+const watchInterval = 2 * time.Second
+
+// engineHolder holds the engine serving new requests.  Requests keep using the engine they started
+// with when it is swapped.
+type engineHolder struct {
+	value atomic.Value
+}
+
+func (h *engineHolder) get() *graphql.Engine {
+	return h.value.Load().(*graphql.Engine)
+}
+
+func (h *engineHolder) set(engine *graphql.Engine) {
+	h.value.Store(engine)
+}
+
+func (h *engineHolder) ServeGraphQLStream(request *graphql.Request) graphql.ResponseStream {
+	return h.get().ServeGraphQLStream(request)
+}
+
+// reloader rebuilds the engine when the config file or the openapi document changes.
+type reloader struct {
+	configFile string
+	// remoteInterval is how often remote openapi documents are checked, they are only checked on SIGHUP if 0.
+	remoteInterval time.Duration
+	build          func(config Config) (*graphql.Engine, error)
+	engine         *engineHolder
+
+	fingerprint []byte
+	// failed is the fingerprint of the last config that could not be built, it is not built again.
+	failed  []byte
+	specURL string
+	spec    []byte
+}
+
+// load loads the config and the openapi document, and builds the engine if they changed since the
+// last load, or if force is true.  Remote documents are only fetched again when remote is true.  The
+// files of the external $refs of the document are not checked for changes, only a forced load picks
+// them up.
+func (r *reloader) load(remote bool, force bool) (bool, error) {
+	config, err := LoadConfig(r.configFile)
+	if err != nil {
+		return false, err
+	}
+	// the config is fingerprinted once interpolated, so that changed environment variables and secret
+	// files reload it too.
+	configData, err := json.Marshal(config)
+	if err != nil {
+		return false, err
+	}
+
+	spec := r.spec
+	if config.Openapi.URL != r.specURL || remote || !isRemoteURL(config.Openapi.URL) {
+		spec, err = apis.ReadOpenapiDocument(config.Openapi)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	hash := sha256.New()
+	hash.Write(configData)
+	hash.Write([]byte{0})
+	hash.Write(spec)
+	fingerprint := hash.Sum(nil)
+	if !force && (bytes.Equal(fingerprint, r.fingerprint) || bytes.Equal(fingerprint, r.failed)) {
+		return false, nil
+	}
+
+	// build from the document we read so that it is not fetched twice.
+	config.Openapi.OpenapiDocument = spec
+	engine, err := r.build(config)
+	if err != nil {
+		r.failed = fingerprint
+		return false, err
+	}
+	r.engine.set(engine)
+	r.fingerprint = fingerprint
+	r.specURL = config.Openapi.URL
+	r.spec = spec
+	return true, nil
+}
+
+// watch reloads the engine when the local files change, or when the remote interval expires.  A SIGHUP
+// signal always reloads it.  A failed reload keeps the current engine.
+func (r *reloader) watch() {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	local := time.NewTicker(watchInterval)
+	defer local.Stop()
+	var remote <-chan time.Time
+	if r.remoteInterval > 0 {
+		ticker := time.NewTicker(r.remoteInterval)
+		defer ticker.Stop()
+		remote = ticker.C
+	}
+
+	for {
+		fetch, force := true, false
+		select {
+		case <-hangup:
+			force = true
+		case <-remote:
+		case <-local.C:
+			fetch = false
+		}
+		reloaded, err := r.load(fetch, force)
+		if err != nil {
+			log.Printf("reload failed, still serving the previous schema: %v", err)
+		} else if reloaded {
+			log.Printf("reloaded the GraphQL schema")
+		}
+	}
+}
+
+func isRemoteURL(location string) bool {
+	u, err := url.Parse(location)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
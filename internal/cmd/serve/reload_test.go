@@ -0,0 +1,57 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chirino/graphql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadOnSecretChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	secretFile := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(secretFile, []byte("one"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "openapi.yaml"), []byte("openapi: 3.0.0\n"), 0644))
+	configFile := filepath.Join(dir, "graphql-4-apis.yaml")
+	require.NoError(t, ioutil.WriteFile(configFile, []byte(`
+spec:
+  url: `+filepath.Join(dir, "openapi.yaml")+`
+api:
+  url: http://localhost
+  bearer-token: file:`+secretFile+`
+`), 0644))
+
+	tokens := []string{}
+	r := &reloader{
+		configFile: configFile,
+		engine:     &engineHolder{},
+		build: func(c Config) (*graphql.Engine, error) {
+			tokens = append(tokens, c.APIBase.BearerToken)
+			return graphql.New(), nil
+		},
+	}
+	reloaded, err := r.load(true, true)
+	require.NoError(t, err)
+	require.True(t, reloaded)
+	reloaded, err = r.load(false, false)
+	require.NoError(t, err)
+	require.False(t, reloaded)
+
+	// the config file did not change, the secret it refers to did.
+	require.NoError(t, ioutil.WriteFile(secretFile, []byte("two"), 0600))
+	reloaded, err = r.load(false, false)
+	require.NoError(t, err)
+	require.True(t, reloaded)
+
+	// a forced load rebuilds the engine even when nothing changed.
+	reloaded, err = r.load(false, true)
+	require.NoError(t, err)
+	require.True(t, reloaded)
+	require.Equal(t, []string{"one", "two", "two"}, tokens)
+}
@@ -0,0 +1,77 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/chirino/graphql-4-apis/internal/cmd/root"
+	api "github.com/chirino/graphql-4-apis/internal/cmd/serve"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/spf13/cobra"
+)
+
+var (
+	Command = &cobra.Command{
+		Use:   "validate",
+		Short: "Checks the config file and the openapi document it references",
+		Long: `Checks that the config file only holds known settings with values of the right type, that the
+openapi document it references loads, and that the GraphQL schema can be generated from it.  The API
+is not called.  The command exits with status 1 when a check fails, which makes it usable as a CI check.
+
+The --json-schema option prints the JSON Schema of the config file instead, editors can use it to
+validate and complete the config file.`,
+		Run: run,
+	}
+	ConfigFile = ""
+	JSONSchema = false
+)
+
+func init() {
+	Command.Flags().StringVar(&ConfigFile, "config", "graphql-4-apis.yaml", "path to the config file to check")
+	Command.Flags().BoolVar(&JSONSchema, "json-schema", false, "print the JSON Schema of the config file and exit")
+	root.Command.AddCommand(Command)
+}
+
+func run(_ *cobra.Command, _ []string) {
+	vebosityFmt := "%v"
+	if root.Verbose {
+		vebosityFmt = "%+v\n"
+	}
+
+	if JSONSchema {
+		data, err := json.MarshalIndent(api.ConfigJSONSchema(), "", "  ")
+		if err != nil {
+			log.Fatalf(vebosityFmt, err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	config, err := api.LoadConfig(ConfigFile)
+	if err != nil {
+		log.Fatalf(vebosityFmt, err)
+	}
+
+	if config.Openapi.URL == "" {
+		log.Fatalf("invalid config file %s: spec.url is not set", ConfigFile)
+	}
+	spec, err := apis.ReadOpenapiDocument(config.Openapi)
+	if err != nil {
+		log.Fatalf("could not read the openapi document %s: "+vebosityFmt, config.Openapi.URL, err)
+	}
+	config.Openapi.OpenapiDocument = spec
+	_, err = apis.LoadOpenApiV2orV3Doc(config.Openapi)
+	if err != nil {
+		log.Fatalf("invalid openapi document %s: "+vebosityFmt, config.Openapi.URL, err)
+	}
+
+	if config.Webhooks.Enabled {
+		config.WebhookReceiver = apis.NewWebhookReceiver()
+	}
+	_, err = apis.CreateGatewayEngine(config.Config)
+	if err != nil {
+		log.Fatalf("could not generate the GraphQL schema: "+vebosityFmt, err)
+	}
+	fmt.Printf("%s is valid\n", ConfigFile)
+}
@@ -41,7 +41,10 @@ func ListenAndServe(config apis.Config) {
 	engine.Root = root(0)
 
 	addr := ":8080"
-	http.Handle("/graphql", &httpgql.Handler{ServeGraphQLStream: engine.ServeGraphQLStream})
+	http.Handle("/graphql", &apis.MultipartHandler{
+		Next:               &httpgql.Handler{ServeGraphQLStream: engine.ServeGraphQLStream},
+		ServeGraphQLStream: engine.ServeGraphQLStream,
+	})
 	http.Handle("/", graphiql.New("ws://localhost"+addr+"/graphql", true))
 	fmt.Println("GraphQL service running at http://localhost" + addr + "/graphql")
 	fmt.Println("GraphiQL UI running at http://localhost" + addr + "/")
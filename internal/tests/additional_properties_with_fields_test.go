@@ -0,0 +1,65 @@
+package tests_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdditionalPropertiesWithFields(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		AssertEquals(t, `{"labels":{"app":"web","tier":1},"name":"test","replicas":3}`, string(data))
+		_, _ = w.Write([]byte(`{"name":"test","replicas":3,"timeout":30,"labels":{"app":"web","tier":"front"}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	messages := bytes.NewBuffer(nil)
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "additional_properties_with_fields_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: server.URL,
+		},
+		Log: log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, "", messages.String())
+
+	actual := engine.Schema.String()
+	if os.ExpandEnv("${GENERATE_TEST_GRAPHQL_FILES}") == "true" {
+		ioutil.WriteFile("additional_properties_with_fields_test.graphql", []byte(actual), 0644)
+	}
+	file, err := ioutil.ReadFile("additional_properties_with_fields_test.graphql")
+	require.NoError(t, err)
+	AssertEquals(t, string(file), actual)
+
+	response := engine.ServeGraphQL(&graphql.Request{
+		Query: `mutation{
+			example(body:{
+				name:"test",
+				labels:{app:"web", additionalProperties:[{key:"tier", value:"1"}]},
+				additionalProperties:[{key:"replicas", value:3}]
+			}) {
+				name
+				labels { app additionalProperties { key value } }
+				additionalProperties { key value }
+			}
+		}`,
+	})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"example":{"name":"test","labels":{"app":"web","additionalProperties":[{"key":"tier","value":"\"front\""}]},"additionalProperties":[{"key":"replicas","value":3},{"key":"timeout","value":30}]}}`, string(response.Data))
+}
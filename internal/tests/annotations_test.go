@@ -0,0 +1,58 @@
+package tests_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultsExamplesAndDeprecations(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pets", func(w http.ResponseWriter, r *http.Request) {
+		AssertEquals(t, "limit=20&order=name", r.URL.RawQuery)
+		_, _ = w.Write([]byte(`[{"name":"fido","tag":"dog"}]`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	messages := bytes.NewBuffer(nil)
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "annotations_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: server.URL,
+		},
+		Log: log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, "", messages.String())
+
+	actual := engine.Schema.String()
+	if os.ExpandEnv("${GENERATE_TEST_GRAPHQL_FILES}") == "true" {
+		ioutil.WriteFile("annotations_test.graphql", []byte(actual), 0644)
+	}
+	file, err := ioutil.ReadFile("annotations_test.graphql")
+	require.NoError(t, err)
+	AssertEquals(t, string(file), actual)
+
+	response := engine.ServeGraphQL(&graphql.Request{
+		Query: `{ listPets { name tag } }`,
+	})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"listPets":[{"name":"fido","tag":"dog"}]}`, string(response.Data))
+
+	response = engine.ServeGraphQL(&graphql.Request{
+		Query: `{ __type(name: "PetResult") { fields(includeDeprecated: true) { name isDeprecated deprecationReason } } }`,
+	})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"__type":{"fields":[{"name":"name","isDeprecated":false,"deprecationReason":null},{"name":"tag","isDeprecated":true,"deprecationReason":"Use tags."},{"name":"vaccinated","isDeprecated":false,"deprecationReason":null},{"name":"weight","isDeprecated":false,"deprecationReason":null}]}}`, string(response.Data))
+}
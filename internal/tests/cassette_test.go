@@ -0,0 +1,145 @@
+package tests_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/chirino/graphql/httpgql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pets", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == "POST" {
+			data, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			AssertEquals(t, `{"name":"Tom"}`, string(data))
+			w.WriteHeader(201)
+			_, _ = w.Write([]byte(`{"id":"2","name":"Tom"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"id":"1","name":"Rex"}]`))
+	})
+	server := httptest.NewServer(mux)
+
+	dir, err := ioutil.TempDir("", "cassettes")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	recorder, err := apis.NewRecordingTransport(dir, nil)
+	require.NoError(t, err)
+	engine := cassetteTestEngine(t, "mock_test.yaml", server.URL, recorder)
+
+	listPets := `{ listPets(limit: 1) { id name } }`
+	createPet := `mutation { createPet(body: {name: "Tom"}) { id name } }`
+	response := engine.ServeGraphQL(&graphql.Request{Query: listPets})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"listPets":[{"id":"1","name":"Rex"}]}`, string(response.Data))
+	response = engine.ServeGraphQL(&graphql.Request{Query: createPet})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"createPet":{"id":"2","name":"Tom"}}`, string(response.Data))
+	server.Close()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	data, err := ioutil.ReadFile(files[1])
+	require.NoError(t, err)
+	require.Contains(t, string(data), `body: '{"name":"Tom"}'`)
+
+	// the recorded responses are served without the API.
+	replay, err := apis.NewReplayTransport(dir)
+	require.NoError(t, err)
+	engine = cassetteTestEngine(t, "mock_test.yaml", server.URL, replay)
+	response = engine.ServeGraphQL(&graphql.Request{Query: createPet})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"createPet":{"id":"2","name":"Tom"}}`, string(response.Data))
+	response = engine.ServeGraphQL(&graphql.Request{Query: listPets})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"listPets":[{"id":"1","name":"Rex"}]}`, string(response.Data))
+
+	// requests that were not recorded fail.
+	response = engine.ServeGraphQL(&graphql.Request{Query: `{ listPets(limit: 2) { id } }`})
+	require.Len(t, response.Errors, 1)
+	require.Contains(t, response.Errors[0].Message, "no recorded interaction matches the request: GET /pets?limit=2")
+}
+
+func TestRecordAndReplayUpload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"parts":["` + r.FormValue("user") + `"]}`))
+	}))
+
+	dir, err := ioutil.TempDir("", "cassettes")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// every multipart request gets a new random boundary.
+	upload := func(engine *graphql.Engine) string {
+		gateway := httptest.NewServer(&apis.MultipartHandler{
+			Next:               &httpgql.Handler{ServeGraphQLStream: engine.ServeGraphQLStream},
+			ServeGraphQLStream: engine.ServeGraphQLStream,
+		})
+		defer gateway.Close()
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		require.NoError(t, writer.WriteField("operations", `{"query":"mutation($file:Upload!){ uploadAvatar(body:{user:\"hiram\", avatar:$file}) { parts } }","variables":{"file":null}}`))
+		require.NoError(t, writer.WriteField("map", `{"0":["variables.file"]}`))
+		part, err := writer.CreateFormFile("0", "avatar.png")
+		require.NoError(t, err)
+		_, err = part.Write([]byte("PNG DATA"))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+
+		resp, err := http.Post(gateway.URL, writer.FormDataContentType(), body)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	recorder, err := apis.NewRecordingTransport(dir, nil)
+	require.NoError(t, err)
+	AssertEquals(t, `{"data":{"uploadAvatar":{"parts":["hiram"]}}}`+"\n", upload(cassetteTestEngine(t, "upload_test.yaml", server.URL, recorder)))
+	server.Close()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	data, err := ioutil.ReadFile(files[0])
+	require.NoError(t, err)
+	require.Contains(t, string(data), "--cassette-boundary")
+	require.Contains(t, string(data), `body: '{"parts":["hiram"]}'`)
+
+	replay, err := apis.NewReplayTransport(dir)
+	require.NoError(t, err)
+	AssertEquals(t, `{"data":{"uploadAvatar":{"parts":["hiram"]}}}`+"\n", upload(cassetteTestEngine(t, "upload_test.yaml", server.URL, replay)))
+}
+
+func cassetteTestEngine(t *testing.T, spec string, url string, transport http.RoundTripper) *graphql.Engine {
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: spec,
+		},
+		APIBase: apis.EndpointOptions{
+			URL:    url,
+			Client: &http.Client{Transport: transport},
+		},
+		Log: log.New(ioutil.Discard, "", 0),
+	})
+	require.NoError(t, err)
+	return engine
+}
@@ -0,0 +1,42 @@
+package tests_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectionFormat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"url": r.URL.EscapedPath() + "?" + r.URL.RawQuery})
+	}))
+	defer server.Close()
+
+	messages := bytes.NewBuffer(nil)
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "collection_format_test.json",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: server.URL,
+		},
+		Log: log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, "", messages.String())
+
+	response := engine.ServeGraphQL(&graphql.Request{
+		Query: `{
+			items(ids:["a/1","b"], csv:["a","b"], ssv:["a","b"], tsv:["a","b"], pipes:["a","b"], multi:[1,2]) { url }
+		}`,
+	})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"items":{"url":"/items/a%2F1,b?csv=a%2Cb\u0026multi=1\u0026multi=2\u0026pipes=a%7Cb\u0026ssv=a+b\u0026tsv=a%09b"}}`, string(response.Data))
+}
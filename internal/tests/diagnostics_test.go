@@ -0,0 +1,58 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"testing"
+
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnostics(t *testing.T) {
+	diagnostics := &apis.Diagnostics{}
+	_, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "diagnostics_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: "http://localhost",
+		},
+		Diagnostics: diagnostics,
+		Log:         log.New(ioutil.Discard, "", 0),
+	})
+	require.NoError(t, err)
+
+	actual, err := json.MarshalIndent(diagnostics, "", "  ")
+	require.NoError(t, err)
+	AssertEquals(t, `{
+  "diagnostics": [
+    {
+      "severity": "warning",
+      "pointer": "/paths/~1pets~1{id}/get",
+      "reason": "Duplicate operation id found: listPets"
+    },
+    {
+      "severity": "warning",
+      "pointer": "/components/schemas/Pet/properties/owner/x-links/pets",
+      "path": "PetOwnerResult.pets",
+      "reason": "dropping x-links field 'PetOwnerResult.pets': could not find operation with id: missingOperation"
+    },
+    {
+      "severity": "warning",
+      "pointer": "/paths/~1pets/get/parameters/0/schema/default",
+      "path": "Query.listPets(limit)",
+      "reason": "dropping Query.listPets field parameter 'limit' default value: default value ten is not a valid Int"
+    },
+    {
+      "severity": "error",
+      "pointer": "/paths/~1pets~1{id}/get",
+      "path": "Query.listPets",
+      "reason": "field already exists: listPets"
+    }
+  ]
+}`, string(actual))
+	require.Equal(t, 1, diagnostics.Count(apis.SeverityError))
+	require.Equal(t, 4, diagnostics.Count(apis.SeverityWarning))
+}
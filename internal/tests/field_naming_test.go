@@ -0,0 +1,71 @@
+package tests_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldNaming(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/animals/a1", func(w http.ResponseWriter, r *http.Request) {
+		AssertEquals(t, "r1", r.Header.Get("X-Request-Id"))
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		AssertEquals(t, `{"animal_type_id":2,"owner":{"first_name":"hiram"}}`, string(data))
+		_, _ = w.Write([]byte(`{"animal_type_id":2,"display_name":"fido","owner":{"first_name":"hiram"}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	messages := bytes.NewBuffer(nil)
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "field_naming_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: server.URL,
+		},
+		FieldNaming: apis.NamingCamelCase,
+		Log:         log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, "", messages.String())
+
+	actual := engine.Schema.String()
+	if os.ExpandEnv("${GENERATE_TEST_GRAPHQL_FILES}") == "true" {
+		ioutil.WriteFile("field_naming_test.graphql", []byte(actual), 0644)
+	}
+	file, err := ioutil.ReadFile("field_naming_test.graphql")
+	require.NoError(t, err)
+	AssertEquals(t, string(file), actual)
+
+	response := engine.ServeGraphQL(&graphql.Request{
+		Query: `mutation {
+			updateAnimal(animalId: "a1", xRequestId: "r1", body: {animalTypeId: 2, owner: {firstName: "hiram"}}) {
+				animalTypeId
+				displayName
+				owner { firstName }
+			}
+		}`,
+	})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"updateAnimal":{"animalTypeId":2,"displayName":"fido","owner":{"firstName":"hiram"}}}`, string(response.Data))
+
+	_, err = apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "field_naming_test.yaml",
+		},
+		FieldNaming: "kebab-case",
+	})
+	require.Error(t, err)
+}
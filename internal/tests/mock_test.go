@@ -0,0 +1,76 @@
+package tests_test
+
+import (
+	"io/ioutil"
+	"log"
+	"testing"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMock(t *testing.T) {
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "mock_test.yaml",
+		},
+		Mock: apis.MockOptions{
+			Enabled: true,
+		},
+		Log: log.New(ioutil.Discard, "", 0),
+	})
+	require.NoError(t, err)
+
+	// the generated data does not change between requests.
+	query := `{ listPets(limit: 5) { id name status born weight } }`
+	response := engine.ServeGraphQL(&graphql.Request{Query: query})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"listPets":[{"id":"19dd5cfe-b227-4de7-b479-7cbff3c76f00","name":"name-470","status":"available","born":"2021-04-09","weight":28.53},{"id":"8e8a55d2-6469-403b-8091-451a9066c714","name":"name-551","status":"sold","born":"2021-07-16","weight":3.26}]}`, string(response.Data))
+	response = engine.ServeGraphQL(&graphql.Request{Query: query})
+	AssertEquals(t, `{"listPets":[{"id":"19dd5cfe-b227-4de7-b479-7cbff3c76f00","name":"name-470","status":"available","born":"2021-04-09","weight":28.53},{"id":"8e8a55d2-6469-403b-8091-451a9066c714","name":"name-551","status":"sold","born":"2021-07-16","weight":3.26}]}`, string(response.Data))
+
+	// examples are returned as is.
+	response = engine.ServeGraphQL(&graphql.Request{
+		Query: `{ getPet(id: "1") { id name status } }`,
+	})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"getPet":{"id":"7","name":"Rex","status":"available"}}`, string(response.Data))
+
+	// mutations echo their input.
+	response = engine.ServeGraphQL(&graphql.Request{
+		Query: `mutation { createPet(body: {name: "Tom", status: "sold"}) { name status } }`,
+	})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"createPet":{"name":"Tom","status":"sold"}}`, string(response.Data))
+
+	// and validate it.
+	response = engine.ServeGraphQL(&graphql.Request{
+		Query: `mutation { createPet(body: {name: "T"}) { name } }`,
+	})
+	require.Len(t, response.Errors, 1)
+	require.Contains(t, response.Errors[0].Message, "invalid arguments: body")
+
+	// integers bounded by the int64 limits are generated.
+	response = engine.ServeGraphQL(&graphql.Request{
+		Query: `{ getCounter { value } }`,
+	})
+	require.Empty(t, response.Errors)
+	require.Contains(t, string(response.Data), `{"getCounter":{"value":`)
+
+	// the seed changes the generated data.
+	engine, err = apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "mock_test.yaml",
+		},
+		Mock: apis.MockOptions{
+			Enabled: true,
+			Seed:    42,
+		},
+		Log: log.New(ioutil.Discard, "", 0),
+	})
+	require.NoError(t, err)
+	response = engine.ServeGraphQL(&graphql.Request{Query: query})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"listPets":[{"id":"57cf9fa1-8a29-4d82-b64f-59b3cecce1d9","name":"name-597","status":"sold","born":"2021-07-24","weight":1.37},{"id":"a86fe989-9afd-4bc0-9f7e-b475ec166375","name":"name-929","status":"available","born":"2021-03-10","weight":10.72}]}`, string(response.Data))
+}
@@ -0,0 +1,101 @@
+package tests_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaces(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pets", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			_, _ = w.Write([]byte(`{"name":"fido"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"name":"fido"}]`))
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`ok`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	messages := bytes.NewBuffer(nil)
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "namespace_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: server.URL,
+		},
+		Namespaces: apis.NamespaceOptions{
+			Enabled: true,
+			Operations: map[string]string{
+				"listStores": "shop",
+			},
+		},
+		Log: log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, "", messages.String())
+
+	actual := engine.Schema.String()
+	if os.ExpandEnv("${GENERATE_TEST_GRAPHQL_FILES}") == "true" {
+		ioutil.WriteFile("namespace_test.graphql", []byte(actual), 0644)
+	}
+	file, err := ioutil.ReadFile("namespace_test.graphql")
+	require.NoError(t, err)
+	AssertEquals(t, string(file), actual)
+
+	response := engine.ServeGraphQL(&graphql.Request{
+		Query: `{ health pets { listPets { name } } }`,
+	})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"health":"ok","pets":{"listPets":[{"name":"fido"}]}}`, string(response.Data))
+
+	// mutations stay on the root type so that they run serially.
+	response = engine.ServeGraphQL(&graphql.Request{
+		Query: `mutation { addPet(body: {name: "fido"}) { name } }`,
+	})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"addPet":{"name":"fido"}}`, string(response.Data))
+}
+
+func TestMutationNamespaces(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pets", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"fido"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "namespace_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: server.URL,
+		},
+		Namespaces: apis.NamespaceOptions{
+			Enabled:   true,
+			Mutations: true,
+		},
+		Log: log.New(ioutil.Discard, "", 0),
+	})
+	require.NoError(t, err)
+
+	response := engine.ServeGraphQL(&graphql.Request{
+		Query: `mutation { pets { addPet(body: {name: "fido"}) { name } } }`,
+	})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"pets":{"addPet":{"name":"fido"}}}`, string(response.Data))
+}
@@ -0,0 +1,71 @@
+package tests_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/chirino/graphql/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeNaming(t *testing.T) {
+	messages := bytes.NewBuffer(nil)
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "naming_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: "http://localhost",
+		},
+		Log: log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, "", messages.String())
+
+	actual := engine.Schema.String()
+	if os.ExpandEnv("${GENERATE_TEST_GRAPHQL_FILES}") == "true" {
+		ioutil.WriteFile("naming_test.graphql", []byte(actual), 0644)
+	}
+	file, err := ioutil.ReadFile("naming_test.graphql")
+	require.NoError(t, err)
+	AssertEquals(t, string(file), actual)
+
+	// the identical inline results of the operations share one type, named after the smallest position.
+	query := engine.Schema.Types["Query"].(*schema.Object)
+	for _, name := range []string{"getPet", "listOwners", "listPets"} {
+		AssertEquals(t, "QueryGetPetResult", schema.DeepestType(query.Fields.Get(name).Type).String())
+	}
+	require.Nil(t, engine.Schema.Types["QueryListPetsResult"])
+	require.Nil(t, engine.Schema.Types["QueryListOwnersResult"])
+}
+
+func TestCustomTypeNaming(t *testing.T) {
+	messages := bytes.NewBuffer(nil)
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "naming_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: "http://localhost",
+		},
+		TypeName: func(ref string, defaultName string, s *openapi3.Schema) string {
+			if s.Properties["name"] != nil {
+				return "Pet"
+			}
+			return strings.TrimPrefix(defaultName, "Query")
+		},
+		Log: log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, "", messages.String())
+
+	require.NotNil(t, engine.Schema.Types["PetResult"])
+	require.NotNil(t, engine.Schema.Types["PetInput"])
+	require.NotNil(t, engine.Schema.Types["ListPetsArgPageInput"])
+}
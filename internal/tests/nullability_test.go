@@ -0,0 +1,60 @@
+package tests_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNullability(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		data, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		AssertEquals(t, `{"name":"hiram","password":"secret"}`, string(data))
+		_, _ = w.Write([]byte(`{"id":"1","name":"hiram","nickname":null}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	messages := bytes.NewBuffer(nil)
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "nullability_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: server.URL,
+		},
+		RequiredResults: true,
+		Log:             log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, "", messages.String())
+
+	actual := engine.Schema.String()
+	if os.ExpandEnv("${GENERATE_TEST_GRAPHQL_FILES}") == "true" {
+		ioutil.WriteFile("nullability_test.graphql", []byte(actual), 0644)
+	}
+	file, err := ioutil.ReadFile("nullability_test.graphql")
+	require.NoError(t, err)
+	AssertEquals(t, string(file), actual)
+
+	response := engine.ServeGraphQL(&graphql.Request{
+		Query: `mutation { createUser(body: {name: "hiram", nickname: null, password: "secret"}) { id name nickname } }`,
+	})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"createUser":{"id":"1","name":"hiram","nickname":null}}`, string(response.Data))
+
+	response = engine.ServeGraphQL(&graphql.Request{
+		Query: `mutation { createUser(body: {nickname: "hi"}) { id } }`,
+	})
+	require.Len(t, response.Errors, 1)
+}
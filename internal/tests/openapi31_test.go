@@ -0,0 +1,63 @@
+package tests_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/chirino/graphql/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPI31(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pets/1", func(w http.ResponseWriter, r *http.Request) {
+		AssertEquals(t, "limit=2&version=v1.0", r.URL.RawQuery)
+		_, _ = w.Write([]byte(`{"id":"1","kind":"pet","legs":4,"version":"v1.0","name":null,"location":[1.5,2],"owner":{"name":"hiram"},"tags":["Zm9v"]}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	messages := bytes.NewBuffer(nil)
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "openapi31_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: server.URL,
+		},
+		Log: log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, "", messages.String())
+
+	actual := engine.Schema.String()
+	if os.ExpandEnv("${GENERATE_TEST_GRAPHQL_FILES}") == "true" {
+		ioutil.WriteFile("openapi31_test.graphql", []byte(actual), 0644)
+	}
+	file, err := ioutil.ReadFile("openapi31_test.graphql")
+	require.NoError(t, err)
+	AssertEquals(t, string(file), actual)
+
+	// string consts are single value enums, renamed when the value is not a valid GraphQL name.
+	kind, ok := engine.Schema.Types["PetKindEnum"].(*schema.Enum)
+	require.True(t, ok)
+	require.Len(t, kind.Values, 1)
+	AssertEquals(t, "pet", kind.Values[0].Name)
+	version, ok := engine.Schema.Types["PetVersionEnum"].(*schema.Enum)
+	require.True(t, ok)
+	require.Len(t, version.Values, 1)
+	AssertEquals(t, "v1_0", version.Values[0].Name)
+
+	response := engine.ServeGraphQL(&graphql.Request{
+		Query: `{ getPet(id: "1", limit: 2, version: v1_0) { id kind legs version name location owner { name } tags } }`,
+	})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"getPet":{"id":"1","kind":"pet","legs":4,"version":"v1_0","name":null,"location":[1.5,2],"owner":{"name":"hiram"},"tags":["Zm9v"]}}`, string(response.Data))
+}
@@ -0,0 +1,126 @@
+package tests_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/chirino/graphql/httpgql"
+	"github.com/chirino/graphql/schema"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollingSubscription(t *testing.T) {
+	mu := sync.Mutex{}
+	polls := map[string]int{}
+	m := mux.NewRouter()
+	m.HandleFunc("/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		mu.Lock()
+		polls[id]++
+		count := polls[id]
+		mu.Unlock()
+
+		// the job changes state every 3 polls.
+		state := "pending"
+		if count > 3 {
+			state = "running"
+		}
+		if count > 6 {
+			state = "done"
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id": id, "state": state})
+	})
+	m.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"state": "ok"})
+	})
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	messages := bytes.NewBuffer(nil)
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "poll_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: server.URL,
+		},
+		Polling: apis.PollingOptions{
+			Operations: map[string]string{
+				"getJob": "5ms",
+			},
+		},
+		Log: log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, "", messages.String())
+
+	subscription := engine.Schema.Types["Subscription"].(*schema.Object)
+	fields := []string{}
+	for _, f := range subscription.Fields {
+		fields = append(fields, f.Name)
+	}
+	AssertEquals(t, []string{"getJob", "getStatus"}, fields)
+
+	// Two subscribers with the same arguments share the same poller.
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	defer cancel1()
+	stream1 := engine.ServeGraphQLStream(&graphql.Request{
+		Context: ctx1,
+		Query:   `subscription { getJob(id: "a") { id state } }`,
+	})
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	stream2 := engine.ServeGraphQLStream(&graphql.Request{
+		Context: ctx2,
+		Query:   `subscription { getJob(id: "a") { state } }`,
+	})
+
+	// Only the changes get pushed to the subscribers.
+	for _, state := range []string{"pending", "running", "done"} {
+		response := <-stream1
+		require.NoError(t, response.Error())
+		AssertEquals(t, `{"getJob":{"id":"a","state":"`+state+`"}}`, string(response.Data))
+	}
+	for _, state := range []string{"running", "done"} {
+		response := <-stream2
+		if string(response.Data) == `{"getJob":{"state":"pending"}}` {
+			// the second subscriber may have joined before the first poll completed.
+			response = <-stream2
+		}
+		require.NoError(t, response.Error())
+		AssertEquals(t, `{"getJob":{"state":"`+state+`"}}`, string(response.Data))
+	}
+
+	cancel1()
+	cancel2()
+	for range stream1 {
+	}
+	for range stream2 {
+	}
+	mu.Lock()
+	require.Len(t, polls, 1)
+	mu.Unlock()
+
+	// Subscriptions are also available over the websocket transport.
+	gqlServer := httptest.NewServer(&httpgql.Handler{ServeGraphQLStream: engine.ServeGraphQLStream})
+	defer gqlServer.Close()
+	client := httpgql.NewClient(gqlServer.URL)
+	ctx3, cancel3 := context.WithCancel(context.Background())
+	defer cancel3()
+	stream3 := client.ServeGraphQLStream(&graphql.Request{
+		Context: ctx3,
+		Query:   `subscription { getStatus { state } }`,
+	})
+	response := <-stream3
+	require.NoError(t, response.Error())
+	AssertEquals(t, `{"getStatus":{"state":"ok"}}`, string(response.Data))
+}
@@ -0,0 +1,80 @@
+package tests_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestBodyContentTypes(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		contentType := r.Header.Get("Content-Type")
+		body := ""
+		mediaType, params, _ := mime.ParseMediaType(contentType)
+		if mediaType == "multipart/form-data" {
+			// Describe the parts received so that we can assert on them.
+			reader := multipart.NewReader(r.Body, params["boundary"])
+			parts := []string{}
+			for {
+				part, err := reader.NextPart()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				data, err := io.ReadAll(part)
+				require.NoError(t, err)
+				parts = append(parts, fmt.Sprintf("%s(%s)=%s", part.FormName(), part.Header.Get("Content-Type"), string(data)))
+			}
+			contentType = mediaType
+			body = strings.Join(parts, ";")
+		} else {
+			data, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			body = string(data)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"contentType": contentType,
+			"body":        body,
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	messages := bytes.NewBuffer(nil)
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "request_body_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: server.URL,
+		},
+		Log: log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, "request body media type application/yaml is sent as a String\n", messages.String())
+
+	response := engine.ServeGraphQL(&graphql.Request{
+		Query: `mutation{
+			form(body:{name:"fido", tags:["a","b"]}) { contentType body }
+			multipart(body:{name:"fido", tags:["a","b"], owners:[{name:"hiram"}]}) { contentType body }
+			text(body:"hello world") { contentType body }
+			patch(body:{name:"fido"}) { contentType body }
+			yaml(body:"name: fido") { contentType body }
+		}`,
+	})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"form":{"contentType":"application/x-www-form-urlencoded","body":"name=fido\u0026tags=a\u0026tags=b"},"multipart":{"contentType":"multipart/form-data","body":"name(text/x-name)=fido;owners(application/json)=[{\"name\":\"hiram\"}];tags(text/plain)=a;tags(text/plain)=b"},"text":{"contentType":"text/plain","body":"hello world"},"patch":{"contentType":"application/merge-patch+json","body":"{\"name\":\"fido\"}"},"yaml":{"contentType":"application/yaml","body":"name: fido"}}`, string(response.Data))
+}
@@ -0,0 +1,110 @@
+package tests_test
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseMediaTypes(t *testing.T) {
+	accepts := map[string]string{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/vendor", func(w http.ResponseWriter, r *http.Request) {
+		accepts["vendor"] = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		_, _ = w.Write([]byte(`{"id":1,"name":"fido"}`))
+	})
+	mux.HandleFunc("/text", func(w http.ResponseWriter, r *http.Request) {
+		accepts["text"] = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", "text/csv")
+		_, _ = w.Write([]byte("id,name\n1,fido\n"))
+	})
+	mux.HandleFunc("/xml", func(w http.ResponseWriter, r *http.Request) {
+		accepts["xml"] = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<pet id="1"><name>fido</name><tags><tag>a</tag><tag>b</tag></tags></pet>`))
+	})
+	mux.HandleFunc("/binary", func(w http.ResponseWriter, r *http.Request) {
+		accepts["binary"] = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("PNG"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	messages := bytes.NewBuffer(nil)
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "response_types_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: server.URL,
+		},
+		XMLResponses: true,
+		Log:          log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, "", messages.String())
+
+	response := engine.ServeGraphQL(&graphql.Request{
+		Query: `{
+			vendor { id name }
+			text
+			xml { id name tags }
+			binary
+		}`,
+	})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"vendor":{"id":1,"name":"fido"},"text":"id,name\n1,fido\n","xml":{"id":1,"name":"fido","tags":["a","b"]},"binary":"UE5H"}`, string(response.Data))
+	AssertEquals(t, map[string]string{
+		"vendor": "application/vnd.api+json",
+		"text":   "text/csv",
+		"xml":    "application/xml",
+		"binary": "image/png",
+	}, accepts)
+
+	// Binary responses can also be returned as download URLs.
+	engine, err = apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "response_types_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: server.URL,
+		},
+		BinaryResponses: apis.BinaryResponsesURL,
+		Log:             log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	response = engine.ServeGraphQL(&graphql.Request{
+		Query: `{ binary }`,
+	})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"binary":"`+server.URL+`/binary"}`, string(response.Data))
+
+	// the URL is not usable without the credentials, and they must not leak to the client.
+	messages.Reset()
+	engine, err = apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "response_types_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL:         server.URL,
+			BearerToken: "secret",
+		},
+		BinaryResponses: apis.BinaryResponsesURL,
+		Log:             log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, "binary response returned as Base64 instead of a download URL: the operation needs credentials\n", messages.String())
+	response = engine.ServeGraphQL(&graphql.Request{
+		Query: `{ binary }`,
+	})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"binary":"UE5H"}`, string(response.Data))
+}
@@ -0,0 +1,125 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"testing"
+
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/chirino/graphql/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaDiff(t *testing.T) {
+	previous := diffTestSchema(t, "schema_diff_test.yaml")
+	next := diffTestSchema(t, "schema_diff_test_next.yaml")
+
+	require.Empty(t, apis.DiffSchemas(previous, previous))
+
+	actual, err := json.MarshalIndent(apis.DiffSchemas(previous, next), "", "  ")
+	require.NoError(t, err)
+	AssertEquals(t, `[
+  {
+    "criticality": "breaking",
+    "path": "Mutation.deletePet",
+    "description": "field Mutation.deletePet was removed"
+  },
+  {
+    "criticality": "breaking",
+    "path": "NewPetInput.tag",
+    "description": "input field NewPetInput.tag was removed"
+  },
+  {
+    "criticality": "breaking",
+    "path": "NewPetInput.owner",
+    "description": "required input field NewPetInput.owner was added"
+  },
+  {
+    "criticality": "breaking",
+    "path": "PetResult.tag",
+    "description": "field PetResult.tag was removed"
+  },
+  {
+    "criticality": "safe",
+    "path": "PetResult.age",
+    "description": "field PetResult.age was added"
+  },
+  {
+    "criticality": "breaking",
+    "path": "Query.getPet(X_Tenant)",
+    "description": "required argument Query.getPet(X_Tenant) was added"
+  },
+  {
+    "criticality": "dangerous",
+    "path": "Query.listPets(limit)",
+    "description": "argument Query.listPets(limit) default value changed from 10 to 20"
+  },
+  {
+    "criticality": "dangerous",
+    "path": "Query.listPets(offset)",
+    "description": "optional argument Query.listPets(offset) was added"
+  }
+]`, string(actual))
+
+	previous = schema.New()
+	require.NoError(t, previous.Parse(`
+type Query { pets(status: Status, limit: Int!): [Pet] }
+type Pet { id: String, kind: Kind }
+enum Status { AVAILABLE SOLD }
+enum Kind { CAT DOG }`))
+	next = schema.New()
+	require.NoError(t, next.Parse(`
+type Query { pets(status: Status, limit: Int): [Pet!]! }
+type Pet { id: Int, kind: Kind }
+enum Status { AVAILABLE PENDING }
+enum Kind { CAT DOG BIRD }`))
+	actual, err = json.MarshalIndent(apis.DiffSchemas(previous, next), "", "  ")
+	require.NoError(t, err)
+	AssertEquals(t, `[
+  {
+    "criticality": "dangerous",
+    "path": "Kind.BIRD",
+    "description": "enum value BIRD was added to Kind"
+  },
+  {
+    "criticality": "breaking",
+    "path": "Pet.id",
+    "description": "field Pet.id changed type from String to Int"
+  },
+  {
+    "criticality": "safe",
+    "path": "Query.pets",
+    "description": "field Query.pets changed type from [Pet] to [Pet!]!"
+  },
+  {
+    "criticality": "safe",
+    "path": "Query.pets(limit)",
+    "description": "argument Query.pets(limit) changed type from Int! to Int"
+  },
+  {
+    "criticality": "breaking",
+    "path": "Status.SOLD",
+    "description": "enum value SOLD was removed from Status"
+  },
+  {
+    "criticality": "dangerous",
+    "path": "Status.PENDING",
+    "description": "enum value PENDING was added to Status"
+  }
+]`, string(actual))
+}
+
+func diffTestSchema(t *testing.T, file string) *schema.Schema {
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: file,
+		},
+		APIBase: apis.EndpointOptions{
+			URL: "http://localhost",
+		},
+		Log: log.New(ioutil.Discard, "", 0),
+	})
+	require.NoError(t, err)
+	return engine.Schema
+}
@@ -0,0 +1,162 @@
+package tests_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/stretchr/testify/require"
+)
+
+// requestRecorder answers every request with an empty list and keeps the requested URLs.
+type requestRecorder struct {
+	urls []string
+}
+
+func (r *requestRecorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	r.urls = append(r.urls, request.URL.String())
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte(`[]`))),
+		Request:    request,
+	}, nil
+}
+
+func serversTestEngine(t *testing.T, specURL string, server apis.ServerOptions, recorder *requestRecorder) (*graphql.Engine, error) {
+	spec, err := ioutil.ReadFile("servers_test.yaml")
+	require.NoError(t, err)
+	return apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL:             specURL,
+			OpenapiDocument: spec,
+		},
+		APIBase: apis.EndpointOptions{
+			Client: &http.Client{Transport: recorder},
+		},
+		Server: server,
+	})
+}
+
+func TestServers(t *testing.T) {
+	const specURL = "https://docs.example.com/specs/openapi.yaml"
+	query := `{ listPets { id } listStores { id } listOrders { id } }`
+
+	recorder := &requestRecorder{}
+	engine, err := serversTestEngine(t, specURL, apis.ServerOptions{}, recorder)
+	require.NoError(t, err)
+	response := engine.ServeGraphQL(&graphql.Request{Query: query})
+	require.Empty(t, response.Errors)
+	require.ElementsMatch(t, []string{
+		"https://us.api.example.com/v1/pets",
+		"https://docs.example.com/specs/stores-api/stores",
+		"https://orders.example.com/orders-api/orders",
+	}, recorder.urls)
+
+	// variables are overridden by the config.
+	recorder = &requestRecorder{}
+	engine, err = serversTestEngine(t, specURL, apis.ServerOptions{
+		Variables: map[string]string{"region": "eu", "basePath": "v2", "host": "localhost:8080"},
+	}, recorder)
+	require.NoError(t, err)
+	response = engine.ServeGraphQL(&graphql.Request{Query: query})
+	require.Empty(t, response.Errors)
+	require.ElementsMatch(t, []string{
+		"https://eu.api.example.com/v2/pets",
+		"https://docs.example.com/specs/stores-api/stores",
+		"https://localhost:8080/orders-api/orders",
+	}, recorder.urls)
+
+	// relative servers are resolved against the location of the openapi document.
+	for _, server := range []apis.ServerOptions{{Index: 1}, {Description: "sandbox"}} {
+		recorder = &requestRecorder{}
+		engine, err = serversTestEngine(t, specURL, server, recorder)
+		require.NoError(t, err)
+		response = engine.ServeGraphQL(&graphql.Request{Query: `{ listPets { id } }`})
+		require.Empty(t, response.Errors)
+		require.Equal(t, []string{"https://docs.example.com/sandbox/pets"}, recorder.urls)
+	}
+
+	_, err = serversTestEngine(t, specURL, apis.ServerOptions{Variables: map[string]string{"region": "asia"}}, recorder)
+	require.EqualError(t, err, "invalid value of the region server variable: asia, expected one of: us, eu")
+	_, err = serversTestEngine(t, specURL, apis.ServerOptions{Index: 2}, recorder)
+	require.EqualError(t, err, "invalid server index 2, the openapi document has 2 servers")
+	_, err = serversTestEngine(t, specURL, apis.ServerOptions{Description: "Staging"}, recorder)
+	require.EqualError(t, err, "the openapi document has no server with the description: Staging")
+	_, err = serversTestEngine(t, "servers_test.yaml", apis.ServerOptions{Index: 1}, recorder)
+	require.EqualError(t, err, "the relative server URL /sandbox can only be resolved for a remote openapi document, configure the api url")
+}
+
+func TestConfiguredAPIURLOverridesServers(t *testing.T) {
+	spec, err := ioutil.ReadFile("servers_test.yaml")
+	require.NoError(t, err)
+	recorder := &requestRecorder{}
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL:             "https://docs.example.com/specs/openapi.yaml",
+			OpenapiDocument: spec,
+		},
+		APIBase: apis.EndpointOptions{
+			URL:    "http://proxy.example.com/api",
+			Client: &http.Client{Transport: recorder},
+		},
+	})
+	require.NoError(t, err)
+
+	// the servers of the paths and operations don't bypass the configured api url.
+	response := engine.ServeGraphQL(&graphql.Request{Query: `{ listPets { id } listStores { id } listOrders { id } }`})
+	require.Empty(t, response.Errors)
+	require.ElementsMatch(t, []string{
+		"http://proxy.example.com/api/pets",
+		"http://proxy.example.com/api/stores",
+		"http://proxy.example.com/api/orders",
+	}, recorder.urls)
+}
+
+func TestServersWithoutURL(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.0
+info:
+  title: Servers
+  version: 1.0.0
+servers:
+  - url: ""
+    description: Unknown
+  - url: https://api.example.com
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      servers:
+        - url: ""
+      responses:
+        '200':
+          description: The pets
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  type: string
+`)
+	recorder := &requestRecorder{}
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL:             "openapi.yaml",
+			OpenapiDocument: spec,
+		},
+		APIBase: apis.EndpointOptions{
+			Client: &http.Client{Transport: recorder},
+		},
+	})
+	require.NoError(t, err)
+	response := engine.ServeGraphQL(&graphql.Request{Query: `{ listPets }`})
+	require.Empty(t, response.Errors)
+	require.Equal(t, []string{"https://api.example.com/pets"}, recorder.urls)
+
+	_, err = apis.ServerURL(nil, nil, nil)
+	require.EqualError(t, err, "the server is not defined")
+}
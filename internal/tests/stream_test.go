@@ -0,0 +1,127 @@
+package tests_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/chirino/graphql/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamingSubscriptions(t *testing.T) {
+	closed := make(chan string, 10)
+	watchParams := make(chan string, 10)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, ": a comment\n\nevent: message\ndata: {\"id\":1,\n")
+		_, _ = fmt.Fprint(w, "data: \"text\":\"hello\"}\n\ndata: {\"id\":2,\"text\":\"world\"}\n\n")
+	})
+	mux.HandleFunc("/logs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for i := 1; ; i++ {
+			_, _ = fmt.Fprintf(w, "{\"id\":%d,\"text\":\"line %d\"}\n", i, i)
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				closed <- "logs"
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+	})
+	mux.HandleFunc("/pods", func(w http.ResponseWriter, r *http.Request) {
+		watchParams <- r.URL.Query().Get("watch") + " " + r.Header.Get("Accept")
+		if r.URL.Query().Get("watch") != "true" {
+			_, _ = fmt.Fprint(w, `{"items":[{"name":"a"}]}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"type":"ADDED","object":{"name":"a"}}`+"\n")
+		_, _ = fmt.Fprint(w, `{"type":"DELETED","object":{"name":"a"}}`+"\n")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	messages := bytes.NewBuffer(nil)
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "stream_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: server.URL,
+		},
+		Log: log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, "", messages.String())
+
+	fieldNames := func(typeName string) []string {
+		names := []string{}
+		for _, f := range engine.Schema.Types[typeName].(*schema.Object).Fields {
+			names = append(names, f.Name)
+		}
+		return names
+	}
+	// only the list operation can also be called as a query.
+	AssertEquals(t, []string{"listPods"}, fieldNames("Query"))
+	AssertEquals(t, []string{"getEvents", "getLogs", "listPods"}, fieldNames("Subscription"))
+
+	// Server-sent events
+	stream := engine.ServeGraphQLStream(&graphql.Request{
+		Query: `subscription { getEvents { id text } }`,
+	})
+	actual := []string{}
+	for response := range stream {
+		require.NoError(t, response.Error())
+		actual = append(actual, string(response.Data))
+	}
+	AssertEquals(t, []string{`{"getEvents":{"id":1,"text":"hello"}}`, `{"getEvents":{"id":2,"text":"world"}}`}, actual)
+
+	// Newline delimited json, the upstream connection is closed when the client unsubscribes.
+	ctx, cancel := context.WithCancel(context.Background())
+	stream = engine.ServeGraphQLStream(&graphql.Request{
+		Context: ctx,
+		Query:   `subscription { getLogs(follow: true) { text } }`,
+	})
+	for i := 1; i <= 3; i++ {
+		response := <-stream
+		require.NoError(t, response.Error())
+		AssertEquals(t, fmt.Sprintf(`{"getLogs":{"text":"line %d"}}`, i), string(response.Data))
+	}
+	cancel()
+	for range stream {
+	}
+	select {
+	case name := <-closed:
+		AssertEquals(t, "logs", name)
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream connection was not closed")
+	}
+
+	// Kubernetes style watches of list operations.
+	response := engine.ServeGraphQL(&graphql.Request{
+		Query: `{ listPods { items { name } } }`,
+	})
+	require.NoError(t, response.Error())
+	AssertEquals(t, `{"listPods":{"items":[{"name":"a"}]}}`, string(response.Data))
+	AssertEquals(t, " application/json", <-watchParams)
+
+	stream = engine.ServeGraphQLStream(&graphql.Request{
+		Query: `subscription { listPods { type object { name } } }`,
+	})
+	actual = []string{}
+	for response := range stream {
+		require.NoError(t, response.Error())
+		actual = append(actual, string(response.Data))
+	}
+	AssertEquals(t, []string{`{"listPods":{"type":"ADDED","object":{"name":"a"}}}`, `{"listPods":{"type":"DELETED","object":{"name":"a"}}}`}, actual)
+	AssertEquals(t, "true application/json", <-watchParams)
+}
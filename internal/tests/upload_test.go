@@ -0,0 +1,163 @@
+package tests_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/chirino/graphql/httpgql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpload(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/avatar", func(w http.ResponseWriter, r *http.Request) {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		reader := multipart.NewReader(r.Body, params["boundary"])
+		parts := []string{}
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			data, err := io.ReadAll(part)
+			require.NoError(t, err)
+			parts = append(parts, fmt.Sprintf("%s:%s:%s:%s", part.FormName(), part.FileName(), part.Header.Get("Content-Type"), string(data)))
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"parts": parts})
+	})
+	upstream := httptest.NewServer(mux)
+	defer upstream.Close()
+
+	messages := bytes.NewBuffer(nil)
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "upload_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: upstream.URL,
+		},
+		Log: log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, "", messages.String())
+	require.NotNil(t, engine.Schema.Types["Upload"])
+
+	gateway := httptest.NewServer(&apis.MultipartHandler{
+		Next:               &httpgql.Handler{ServeGraphQLStream: engine.ServeGraphQLStream},
+		ServeGraphQLStream: engine.ServeGraphQLStream,
+	})
+	defer gateway.Close()
+
+	// Build a request following the GraphQL multipart request spec.
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	require.NoError(t, writer.WriteField("operations", `{"query":"mutation($file:Upload!){ uploadAvatar(body:{user:\"hiram\", avatar:$file}) { parts } }","variables":{"file":null}}`))
+	require.NoError(t, writer.WriteField("map", `{"0":["variables.file"]}`))
+	part, err := writer.CreateFormFile("0", "avatar.png")
+	require.NoError(t, err)
+	_, err = part.Write([]byte("PNG DATA"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	resp, err := http.Post(gateway.URL, writer.FormDataContentType(), body)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	AssertEquals(t, `{"data":{"uploadAvatar":{"parts":["avatar:avatar.png:application/octet-stream:PNG DATA","user::text/plain:hiram"]}}}`+"\n", string(data))
+}
+
+func TestUploadsReadOutOfOrder(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		reader := multipart.NewReader(r.Body, params["boundary"])
+		parts := []string{}
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			data, err := io.ReadAll(part)
+			require.NoError(t, err)
+			prefix := data
+			if len(prefix) > 3 {
+				prefix = prefix[:3]
+			}
+			parts = append(parts, fmt.Sprintf("%s:%s:%d:%s", part.FormName(), part.FileName(), len(data), string(prefix)))
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"parts": parts})
+	})
+	upstream := httptest.NewServer(mux)
+	defer upstream.Close()
+
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "upload_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: upstream.URL,
+		},
+		Log: log.New(ioutil.Discard, "", 0),
+	})
+	require.NoError(t, err)
+	gateway := httptest.NewServer(&apis.MultipartHandler{
+		Next:               &httpgql.Handler{ServeGraphQLStream: engine.ServeGraphQLStream},
+		ServeGraphQLStream: engine.ServeGraphQLStream,
+	})
+	defer gateway.Close()
+
+	post := func(operations string, fileMap string, files ...string) string {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		require.NoError(t, writer.WriteField("operations", operations))
+		require.NoError(t, writer.WriteField("map", fileMap))
+		for i, content := range files {
+			part, err := writer.CreateFormFile(fmt.Sprint(i), fmt.Sprintf("file%d.txt", i))
+			require.NoError(t, err)
+			_, err = part.Write([]byte(content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, writer.Close())
+
+		resp, err := http.Post(gateway.URL, writer.FormDataContentType(), body)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	// larger than what is kept in memory, so that it gets spooled to a file.
+	large := "AAA" + string(bytes.Repeat([]byte("a"), 2<<20))
+	small := "BBB"
+
+	// the parts are sent in the sorted order of the properties, the reverse of the order the client sent the files.
+	actual := post(`{"query":"mutation($a:Upload!, $b:Upload!){ uploadDocuments(body:{first:$b, second:$a}) { parts } }","variables":{"a":null,"b":null}}`,
+		`{"0":["variables.a"],"1":["variables.b"]}`, large, small)
+	AssertEquals(t, `{"data":{"uploadDocuments":{"parts":["first:file1.txt:3:BBB","second:file0.txt:2097155:AAA"]}}}`+"\n", actual)
+
+	// mutation fields are executed serially, the second file is read first.
+	actual = post(`{"query":"mutation($a:Upload!, $b:Upload!){ x: uploadAvatar(body:{user:\"x\", avatar:$b}) { parts } y: uploadAvatar(body:{user:\"y\", avatar:$a}) { parts } }","variables":{"a":null,"b":null}}`,
+		`{"0":["variables.a"],"1":["variables.b"]}`, large, small)
+	AssertEquals(t, `{"data":{"x":{"parts":["avatar:file1.txt:3:BBB","user::1:x"]},"y":{"parts":["avatar:file0.txt:2097155:AAA","user::1:y"]}}}`+"\n", actual)
+
+	// a file used by several variables is read by each of them.
+	actual = post(`{"query":"mutation($a:Upload!, $b:Upload!){ uploadDocuments(body:{first:$a, second:$b}) { parts } }","variables":{"a":null,"b":null}}`,
+		`{"0":["variables.a","variables.b"]}`, large)
+	AssertEquals(t, `{"data":{"uploadDocuments":{"parts":["first:file0.txt:2097155:AAA","second:file0.txt:2097155:AAA"]}}}`+"\n", actual)
+}
@@ -0,0 +1,99 @@
+package tests_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInputValidation(t *testing.T) {
+	calls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pets", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Method == http.MethodPost {
+			_, _ = w.Write([]byte(`{"id":"1","name":"fido"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"id":"1","name":"fido"}]`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	messages := bytes.NewBuffer(nil)
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "validation_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: server.URL,
+		},
+		ValidateInputs: true,
+		Log:            log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, "", messages.String())
+
+	response := engine.ServeGraphQL(&graphql.Request{
+		Query: `{ listPets(limit: 5, name: "fido") { name } }`,
+	})
+	require.Empty(t, response.Errors)
+	AssertEquals(t, `{"listPets":[{"name":"fido"}]}`, string(response.Data))
+	AssertEquals(t, 1, calls)
+
+	response = engine.ServeGraphQL(&graphql.Request{
+		Query: `{ listPets(limit: 500, name: "Fido") { name } }`,
+	})
+	require.Len(t, response.Errors, 1)
+	violations, err := json.Marshal(response.Errors[0].Extensions["violations"])
+	require.NoError(t, err)
+	AssertEquals(t, `[{"path":"limit","reason":"number must be most 100"},{"path":"name","reason":"string doesn't match the regular expression \"^[a-z]+$\""}]`, string(violations))
+	AssertEquals(t, 1, calls)
+
+	response = engine.ServeGraphQL(&graphql.Request{
+		Query: `mutation { addPet(body: {name: "x", age: -1}) { name } }`,
+	})
+	require.Len(t, response.Errors, 1)
+	violations, err = json.Marshal(response.Errors[0].Extensions["violations"])
+	require.NoError(t, err)
+	AssertEquals(t, `[{"path":"body.age","reason":"number must be at least 0"},{"path":"body.name","reason":"minimum string length is 2"}]`, string(violations))
+	AssertEquals(t, 1, calls)
+}
+
+func TestMissingRequiredParameter(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pets", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"id":"1","name":"fido"}]`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "validation_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: server.URL,
+		},
+		Log: log.New(bytes.NewBuffer(nil), "", 0),
+	})
+	require.NoError(t, err)
+
+	// the link has no parent_id to use as the required id path parameter.
+	response := engine.ServeGraphQL(&graphql.Request{
+		Query: `{ listPets { name parent { name } } }`,
+	})
+	require.Len(t, response.Errors, 1)
+	AssertEquals(t, "required path parameter not set: id", response.Errors[0].Message)
+	AssertEquals(t, "id", response.Errors[0].Extensions["argument"])
+	// the engine leaves the list indexes out of the selection paths.
+	AssertEquals(t, []string{"listPets", "parent"}, response.Errors[0].Path)
+	AssertEquals(t, `{"listPets":[{"name":"fido","parent":null}]}`, string(response.Data))
+}
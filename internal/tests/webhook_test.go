@@ -0,0 +1,152 @@
+package tests_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/chirino/graphql"
+	"github.com/chirino/graphql-4-apis/pkg/apis"
+	"github.com/chirino/graphql/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSubscriptions(t *testing.T) {
+	receiver := apis.NewWebhookReceiver()
+	server := httptest.NewServer(http.StripPrefix("/webhooks", receiver))
+	defer server.Close()
+
+	messages := bytes.NewBuffer(nil)
+	engine, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "webhook_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: "http://localhost:8080",
+		},
+		WebhookReceiver: receiver,
+		Webhooks: apis.WebhookOptions{
+			Enabled: true,
+			Secret:  "s3cret",
+		},
+		Log: log.New(messages, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, "", messages.String())
+
+	fields := []string{}
+	for _, f := range engine.Schema.Types["Subscription"].(*schema.Object).Fields {
+		fields = append(fields, f.Name)
+	}
+	AssertEquals(t, []string{"newPet", "subscribeOnData"}, fields)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dataStream := engine.ServeGraphQLStream(&graphql.Request{
+		Context: ctx,
+		Query:   `subscription { subscribeOnData { value } }`,
+	})
+
+	send := func(path string, contentType string, header string, value string, body string) int {
+		req, err := http.NewRequest("POST", server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	post := func(path string, body string) int {
+		return send(path, "application/json", apis.SecretHeader, "s3cret", body)
+	}
+	sign := func(secret string, body string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	// Requests that are not authenticated are rejected.
+	AssertEquals(t, http.StatusUnauthorized, send("/webhooks/subscribeOnData", "application/json", "", "", `{"value":0}`))
+	AssertEquals(t, http.StatusUnauthorized, send("/webhooks/subscribeOnData", "application/json", apis.SecretHeader, "nope", `{"value":0}`))
+	AssertEquals(t, http.StatusUnauthorized, send("/webhooks/subscribeOnData", "application/json", apis.DefaultSignatureHeader, sign("nope", `{"value":0}`), `{"value":0}`))
+
+	// Invalid payloads are rejected.
+	AssertEquals(t, http.StatusUnsupportedMediaType, send("/webhooks/subscribeOnData", "text/plain", apis.SecretHeader, "s3cret", `{"value":0}`))
+	AssertEquals(t, http.StatusBadRequest, post("/webhooks/subscribeOnData", `{"value":"nope"}`))
+	AssertEquals(t, http.StatusBadRequest, post("/webhooks/subscribeOnData", `{}`))
+	AssertEquals(t, http.StatusNotFound, post("/webhooks/unknown", `{}`))
+
+	AssertEquals(t, http.StatusAccepted, post("/webhooks/subscribeOnData", `{"value":1}`))
+	AssertEquals(t, http.StatusAccepted, send("/webhooks/subscribeOnData", "application/json", apis.DefaultSignatureHeader, sign("s3cret", `{"value":2}`), `{"value":2}`))
+	for _, expected := range []string{`{"subscribeOnData":{"value":1}}`, `{"subscribeOnData":{"value":2}}`} {
+		response := <-dataStream
+		require.NoError(t, response.Error())
+		AssertEquals(t, expected, string(response.Data))
+	}
+
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	stream := engine.ServeGraphQLStream(&graphql.Request{
+		Context: ctx2,
+		Query:   `subscription { newPet { name } }`,
+	})
+	AssertEquals(t, http.StatusOK, post("/webhooks/newPet", `{"name":"fido"}`))
+	response := <-stream
+	require.NoError(t, response.Error())
+	AssertEquals(t, `{"newPet":{"name":"fido"}}`, string(response.Data))
+
+	// an engine built from a document without the newPet webhook replaces the received webhooks.
+	data, err := ioutil.ReadFile("webhook_test.yaml")
+	require.NoError(t, err)
+	withoutNewPet := regexp.MustCompile(`(?s)\nwebhooks:.*\ncomponents:`).ReplaceAllString(string(data), "\ncomponents:")
+	_, err = apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL:             "webhook_test.yaml",
+			OpenapiDocument: []byte(withoutNewPet),
+		},
+		APIBase: apis.EndpointOptions{
+			URL: "http://localhost:8080",
+		},
+		WebhookReceiver: receiver,
+		Webhooks: apis.WebhookOptions{
+			Enabled: true,
+			Secret:  "s3cret",
+		},
+		Log: log.New(ioutil.Discard, "", 0),
+	})
+	require.NoError(t, err)
+	AssertEquals(t, http.StatusNotFound, post("/webhooks/newPet", `{"name":"fido"}`))
+	_, open := <-stream
+	AssertEquals(t, false, open)
+
+	// the subscribers of the webhooks that are still received keep their subscription.
+	AssertEquals(t, http.StatusAccepted, post("/webhooks/subscribeOnData", `{"value":3}`))
+	response = <-dataStream
+	require.NoError(t, response.Error())
+	AssertEquals(t, `{"subscribeOnData":{"value":3}}`, string(response.Data))
+}
+
+func TestWebhookReceiverNeedsSecret(t *testing.T) {
+	_, err := apis.CreateGatewayEngine(apis.Config{
+		Openapi: apis.EndpointOptions{
+			URL: "webhook_test.yaml",
+		},
+		APIBase: apis.EndpointOptions{
+			URL: "http://localhost:8080",
+		},
+		WebhookReceiver: apis.NewWebhookReceiver(),
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,113 @@
+package apis
+
+import (
+	"reflect"
+
+	"github.com/chirino/graphql/resolvers"
+	"github.com/chirino/graphql/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+)
+
+// additionalPropertiesField is the name of the field holding the properties of an object
+// that are not declared in its schema.
+const additionalPropertiesField = "additionalProperties"
+
+// addAdditionalPropertiesField adds a field holding the undeclared properties of an object as a list
+// of key value pairs.  Results get the keys that don't match a declared property, and the input pairs
+// are merged back into the object.
+func (builder *builder) addAdditionalPropertiesField(s *openapi3.Schema, pathBasedTypeName string, inputType bool, typeName string, graphqlType interface{}) error {
+	var valueType schema.Type = builder.JSONType()
+	if s.AdditionalProperties != nil {
+		restore := builder.at(builder.pointer + "/additionalProperties")
+		t, err := builder.addGraphQLType(s.AdditionalProperties, pathBasedTypeName+"AdditionalProperties", inputType)
+		restore()
+		if err != nil {
+			return err
+		}
+		valueType = t
+	}
+	wrapper, err := builder.addPropWrapper(builder.draft, valueType, inputType)
+	if err != nil {
+		return err
+	}
+	fieldType := &schema.List{OfType: &schema.NonNull{OfType: wrapper}}
+
+	declared := map[string]bool{}
+	propertyNames(s, declared, map[*openapi3.Schema]bool{})
+
+	if inputType {
+		object := graphqlType.(*schema.InputObject)
+		if object.Fields.Get(additionalPropertiesField) != nil {
+			return errors.Errorf("field already exists: %s", additionalPropertiesField)
+		}
+		object.Fields = append(object.Fields, &schema.InputValue{
+			Desc: desc("The properties not declared by the type."),
+			Name: additionalPropertiesField,
+			Type: fieldType,
+		})
+		builder.addInputConverter(typeName, func(t schema.Type, value interface{}) (interface{}, error) {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return value, nil
+			}
+			additional, _ := object[additionalPropertiesField].(map[string]interface{})
+			delete(object, additionalPropertiesField)
+			for k, v := range additional {
+				if _, found := object[k]; !found {
+					object[k] = v
+				}
+			}
+			return object, nil
+		})
+		return nil
+	}
+
+	object := graphqlType.(*schema.Object)
+	if object.Fields.Get(additionalPropertiesField) != nil {
+		return errors.Errorf("field already exists: %s", additionalPropertiesField)
+	}
+	object.Fields = append(object.Fields, &schema.Field{
+		Desc: desc("The properties not declared by the type."),
+		Name: additionalPropertiesField,
+		Type: fieldType,
+	})
+	builder.resolvers[typeName+":"+additionalPropertiesField] = resolvers.Func(func(request *resolvers.ResolveRequest, _ resolvers.Resolution) resolvers.Resolution {
+		return func() (reflect.Value, error) {
+			parent := request.Parent
+			for parent.Kind() == reflect.Interface || parent.Kind() == reflect.Ptr {
+				parent = parent.Elem()
+			}
+			object, ok := parent.Interface().(map[string]interface{})
+			if !ok || object == nil {
+				return reflect.Value{}, nil
+			}
+			additional := map[string]interface{}{}
+			for k, v := range object {
+				if !declared[k] {
+					additional[k] = v
+				}
+			}
+			return reflect.ValueOf(additional), nil
+		}
+	})
+	return nil
+}
+
+// propertyNames collects the names of the properties declared by a schema.
+func propertyNames(s *openapi3.Schema, names map[string]bool, visited map[*openapi3.Schema]bool) {
+	if s == nil || visited[s] {
+		return
+	}
+	visited[s] = true
+	for name := range s.Properties {
+		names[name] = true
+	}
+	for _, list := range []openapi3.SchemaRefs{s.AllOf, s.AnyOf, s.OneOf} {
+		for _, ref := range list {
+			if ref != nil {
+				propertyNames(ref.Value, names, visited)
+			}
+		}
+	}
+}
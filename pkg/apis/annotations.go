@@ -0,0 +1,153 @@
+package apis
+
+import (
+	"encoding/json"
+	"strconv"
+	"text/scanner"
+
+	"github.com/chirino/graphql/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+)
+
+const deprecatedReasonExtension = "x-deprecated-reason"
+
+// constExtension marks the single value enums that were openapi 3.1 `const` keywords.
+const constExtension = "x-const"
+
+// deprecatedReason returns the x-deprecated-reason extension value, or the GraphQL default reason.
+func deprecatedReason(extensions map[string]interface{}) string {
+	if raw, ok := extensions[deprecatedReasonExtension].(json.RawMessage); ok {
+		reason := ""
+		if err := json.Unmarshal(raw, &reason); err == nil && reason != "" {
+			return reason
+		}
+	}
+	return "No longer supported"
+}
+
+// deprecatedDirectives returns the @deprecated directive of a deprecated field.
+func deprecatedDirectives(deprecated bool, extensions map[string]interface{}) schema.DirectiveList {
+	if !deprecated {
+		return nil
+	}
+	return schema.DirectiveList{
+		&schema.Directive{
+			Name: "deprecated",
+			Args: schema.ArgumentList{
+				{Name: "reason", Value: schema.ToLiteral(deprecatedReason(extensions))},
+			},
+		},
+	}
+}
+
+// annotate appends the example and the deprecation of an argument or input field to its description.
+// Deprecations are only described since the schema does not support directives on input values.
+func annotate(text string, example interface{}, deprecated bool, extensions map[string]interface{}) string {
+	if example != nil {
+		if data, err := json.Marshal(example); err == nil {
+			text = text + "\n\n**example:** `" + string(data) + "`"
+		}
+	}
+	if deprecated {
+		text = text + "\n\n**deprecated:** " + deprecatedReason(extensions)
+	}
+	return text
+}
+
+// constAnnotation describes the value of a `const` schema that is not mapped to a single value enum, since
+// the GraphQL type of the field does not restrict it to that value.
+func constAnnotation(s *openapi3.SchemaRef) string {
+	if s == nil || s.Value == nil || len(s.Value.Enum) != 1 || s.Value.Extensions[constExtension] == nil {
+		return ""
+	}
+	if _, _, ok := constEnumValue(s.Value); ok {
+		return ""
+	}
+	data, err := json.Marshal(s.Value.Enum[0])
+	if err != nil {
+		return ""
+	}
+	return "\n\n**const:** `" + string(data) + "`"
+}
+
+// parameterExample returns the example of a parameter or of its schema.
+func parameterExample(param *openapi3.Parameter) interface{} {
+	if param.Example != nil {
+		return param.Example
+	}
+	if s := getSchema(param); s != nil && s.Value != nil {
+		return s.Value.Example
+	}
+	return nil
+}
+
+// defaultLiteral converts the default value of a schema to a literal of the GraphQL input type.
+func (builder *builder) defaultLiteral(t schema.Type, value interface{}) (schema.Literal, error) {
+	if value == nil {
+		return &schema.NullLit{}, nil
+	}
+	switch t := t.(type) {
+	case *schema.NonNull:
+		return builder.defaultLiteral(t.OfType, value)
+	case *schema.List:
+		values, ok := value.([]interface{})
+		if !ok {
+			// a single value is coerced to a list of that value.
+			values = []interface{}{value}
+		}
+		result := &schema.ListLit{}
+		for _, v := range values {
+			entry, err := builder.defaultLiteral(t.OfType, v)
+			if err != nil {
+				return nil, err
+			}
+			result.Entries = append(result.Entries, entry)
+		}
+		return result, nil
+	case *schema.InputObject:
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			break
+		}
+		result := &schema.ObjectLit{}
+		for _, name := range sortedMapKeys(values) {
+			field := t.Fields.Get(builder.options.fieldName(name))
+			if field == nil {
+				continue
+			}
+			entry, err := builder.defaultLiteral(field.Type, values[name])
+			if err != nil {
+				return nil, err
+			}
+			result.Fields = append(result.Fields, &schema.ObjectLitField{Name: field.Name, Value: entry})
+		}
+		return result, nil
+	case *schema.Scalar:
+		switch t.Name {
+		case "String":
+			if v, ok := value.(string); ok {
+				return schema.ToLiteral(v), nil
+			}
+		case "Int":
+			if v, ok := value.(float64); ok && v == float64(int32(v)) {
+				return schema.ToLiteral(int64(v)), nil
+			}
+		case "Float":
+			if v, ok := value.(float64); ok {
+				return &schema.BasicLit{Type: scanner.Float, Text: strconv.FormatFloat(v, 'f', -1, 64)}, nil
+			}
+		case "Boolean":
+			if v, ok := value.(bool); ok {
+				return &schema.BasicLit{Type: scanner.Ident, Text: strconv.FormatBool(v)}, nil
+			}
+		case "JSON":
+			data, err := json.Marshal(value)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return schema.ToLiteral(string(data)), nil
+		}
+	}
+	return nil, errors.Errorf("default value %v is not a valid %s", value, t.String())
+}
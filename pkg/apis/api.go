@@ -29,18 +29,64 @@ type EndpointOptions struct {
 }
 
 type Config struct {
-	Openapi      EndpointOptions `json:"spec,omitempty",yaml:"spec,omitempty"`
-	APIBase      EndpointOptions `json:"api,omitempty",yaml:"api,omitempty"`
-	QueryType    string
-	MutationType string
-	Log          *log.Logger
+	// Openapi configures how to get the openapi document.  It can be openapi v2 or v3.
+	Openapi EndpointOptions `yaml:"spec,omitempty" json:"spec,omitempty"`
+	// APIBase configures the base URL that API requests will get issued against.
+	APIBase EndpointOptions `yaml:"api,omitempty" json:"api,omitempty"`
+	// Server selects the server of the openapi document used when the APIBase URL is not configured,
+	// and sets the values of the server variables.
+	Server ServerOptions `yaml:"server,omitempty" json:"server,omitempty"`
+	// QueryType is the name of the GraphQL type holding the query fields.
+	QueryType string `yaml:"query-type,omitempty" json:"query-type,omitempty"`
+	// MutationType is the name of the GraphQL type holding the mutation fields.
+	MutationType string `yaml:"mutation-type,omitempty" json:"mutation-type,omitempty"`
+	// SubscriptionType is the name of the GraphQL type holding the polled subscription fields.
+	SubscriptionType string      `yaml:"subscription-type,omitempty" json:"subscription-type,omitempty"`
+	Log              *log.Logger `yaml:"-" json:"-"`
+	// XMLResponses enables decoding XML responses into the types generated from their schema,
+	// otherwise XML responses are returned as a String.
+	XMLResponses bool `yaml:"xml-responses,omitempty" json:"xml-responses,omitempty"`
+	// BinaryResponses selects how binary responses are returned: "base64" (the default) returns
+	// the data as a Base64 scalar, "url" returns the URL the data can be downloaded from.
+	BinaryResponses string `yaml:"binary-responses,omitempty" json:"binary-responses,omitempty"`
+	// ValidateInputs checks the arguments against the constraints of the openapi schemas (patterns,
+	// ranges, lengths, enums, formats...) before calling the API and reports violations as GraphQL errors.
+	ValidateInputs bool `yaml:"validate-inputs,omitempty" json:"validate-inputs,omitempty"`
+	// RequiredResults makes the result fields of the properties listed as required (and not nullable)
+	// in the openapi schemas non-null.  It is off by default since many APIs omit required properties.
+	RequiredResults bool `yaml:"required-results,omitempty" json:"required-results,omitempty"`
+	// FieldNaming is the naming convention of the generated fields and arguments: "as-is" (the default),
+	// "camelCase" or "PascalCase".  Values are mapped back to the openapi names when calling the API.
+	FieldNaming string `yaml:"field-naming,omitempty" json:"field-naming,omitempty"`
+	// Diagnostics collects the problems found while mapping the openapi document when set.
+	Diagnostics *Diagnostics `yaml:"-" json:"-"`
+	// Namespaces groups the query fields, and optionally the mutation fields, by their openapi tags.
+	Namespaces NamespaceOptions `yaml:"namespaces,omitempty" json:"namespaces,omitempty"`
+	// TypeName customizes the names of the generated GraphQL types.
+	TypeName TypeNameFunc `yaml:"-" json:"-"`
+	// Streaming configures the operations that stream their responses, those are exposed as subscriptions.
+	Streaming StreamingOptions `yaml:"streaming,omitempty" json:"streaming,omitempty"`
+	// WebhookReceiver receives the callback and webhook requests sent by the API, when set
+	// they are exposed as subscriptions.
+	WebhookReceiver *WebhookReceiver `yaml:"-" json:"-"`
+	// Webhooks configures how the requests sent to the WebhookReceiver are authenticated.
+	Webhooks WebhookOptions `yaml:"webhooks,omitempty" json:"webhooks,omitempty"`
+	// Polling exposes GET operations as subscriptions that poll the API for changes.
+	Polling PollingOptions `yaml:"polling,omitempty" json:"polling,omitempty"`
+	// Mock serves responses built from the openapi document examples and schemas instead of calling the API.
+	Mock MockOptions `yaml:"mock,omitempty" json:"mock,omitempty"`
+
+	// documentServers is set when the APIBase URL is not configured, the servers of the paths and operations
+	// are then used too.
+	documentServers bool
 }
 
 func CreateGatewayEngine(option Config) (*graphql.Engine, error) {
 	engine := graphql.New()
 	o := Config{
-		QueryType:    "Query",
-		MutationType: "Mutation",
+		QueryType:        "Query",
+		MutationType:     "Mutation",
+		SubscriptionType: "Subscription",
 	}
 	if option.Log != nil {
 		o.Log = option.Log
@@ -53,8 +99,38 @@ func CreateGatewayEngine(option Config) (*graphql.Engine, error) {
 	if option.MutationType != "" {
 		o.MutationType = option.MutationType
 	}
+	if option.SubscriptionType != "" {
+		o.SubscriptionType = option.SubscriptionType
+	}
 	o.Openapi = option.Openapi
 	o.APIBase = option.APIBase
+	o.Server = option.Server
+	o.XMLResponses = option.XMLResponses
+	o.BinaryResponses = option.BinaryResponses
+	o.ValidateInputs = option.ValidateInputs
+	o.RequiredResults = option.RequiredResults
+	o.TypeName = option.TypeName
+	o.FieldNaming = option.FieldNaming
+	o.Namespaces = option.Namespaces
+	o.Diagnostics = option.Diagnostics
+	switch o.FieldNaming {
+	case "", NamingAsIs, NamingCamelCase, NamingPascalCase:
+	default:
+		return nil, errors.New("invalid field naming convention: " + o.FieldNaming)
+	}
+	o.Polling = option.Polling
+	o.Streaming = option.Streaming
+	o.Mock = option.Mock
+	o.WebhookReceiver = option.WebhookReceiver
+	o.Webhooks = option.Webhooks
+	if o.WebhookReceiver != nil {
+		if o.Webhooks.Secret == "" {
+			return nil, errors.New("the webhook receiver needs the webhooks secret that authenticates the API requests")
+		}
+		if o.WebhookReceiver.Log == nil {
+			o.WebhookReceiver.Log = o.Log
+		}
+	}
 
 	doc, err := LoadOpenApiV2orV3Doc(o.Openapi)
 	if err != nil {
@@ -63,14 +139,24 @@ func CreateGatewayEngine(option Config) (*graphql.Engine, error) {
 
 	// If the APIBase.URL is not configured.. try to figure it out from the openapi doc...
 	if o.APIBase.URL == "" {
-		for _, server := range doc.Servers {
-			if server != nil && server.URL != "" {
-				o.APIBase.URL = server.URL
-				break
+		o.documentServers = true
+		server, err := selectServer(doc.Servers, o.Server)
+		if err != nil {
+			return nil, err
+		}
+		if server != nil {
+			o.APIBase.URL, err = ServerURL(server, o.Server.Variables, remoteURL(o.Openapi.URL))
+			if err != nil && !o.Mock.Enabled {
+				return nil, err
 			}
 		}
 	}
 
+	if o.APIBase.URL == "" && o.Mock.Enabled {
+		// the API is not called in mock mode.
+		o.APIBase.URL = "http://localhost"
+	}
+
 	if o.APIBase.URL == "" {
 		return nil, errors.New("api base URL is not configured")
 	}
@@ -0,0 +1,286 @@
+package apis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+)
+
+// requestBodyMediaTypes lists the request body media types we know how to encode
+// in order of preference.
+var requestBodyMediaTypes = []string{
+	"application/json",
+	"application/merge-patch+json",
+	"application/json-patch+json",
+	"application/x-www-form-urlencoded",
+	"multipart/form-data",
+	"text/plain",
+	"application/octet-stream",
+}
+
+// requestBodyContent picks the best supported media type of the operation's request body.
+func requestBodyContent(operation *openapi3.Operation) (string, *openapi3.MediaType) {
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return "", nil
+	}
+	content := operation.RequestBody.Value.Content
+	for _, mediaType := range requestBodyMediaTypes {
+		if c := content.Get(mediaType); c != nil {
+			return mediaType, c
+		}
+	}
+
+	// Fallback to any other vendor json media type...
+	var mediaTypes []string
+	for mediaType := range content {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Strings(mediaTypes)
+	for _, mediaType := range mediaTypes {
+		if isJSONMediaType(mediaType) {
+			return mediaType, content[mediaType]
+		}
+	}
+
+	// Other media types are sent as a raw string, their schema does not describe it.
+	for _, mediaType := range mediaTypes {
+		if content[mediaType] != nil {
+			return mediaType, &openapi3.MediaType{}
+		}
+	}
+	return "", nil
+}
+
+// isRawMediaType returns true for the request body media types that are sent as a raw string.
+func isRawMediaType(mediaType string) bool {
+	for _, known := range requestBodyMediaTypes {
+		if known == mediaType {
+			return false
+		}
+	}
+	return !isJSONMediaType(mediaType)
+}
+
+func isJSONMediaType(mediaType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// encodeRequestBody encodes the converted body argument value using the selected media type. It
+// returns the body and the Content-Type header that should be used to send it.
+func encodeRequestBody(mediaType string, content *openapi3.MediaType, value interface{}) (io.Reader, string, error) {
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		values, err := toFormValues(value)
+		if err != nil {
+			return nil, "", err
+		}
+		return strings.NewReader(values.Encode()), mediaType, nil
+
+	case "multipart/form-data":
+		return encodeMultipartBody(content, value)
+
+	case "application/octet-stream":
+		switch value := value.(type) {
+		case *Upload:
+			return value, value.ContentType(), nil
+		case string:
+			return strings.NewReader(value), mediaType, nil
+		default:
+			return nil, "", errors.Errorf("octet-stream request body must be an upload, got: %T", value)
+		}
+
+	case "text/plain":
+		return encodeRawBody(mediaType, value)
+
+	default:
+		if isRawMediaType(mediaType) {
+			return encodeRawBody(mediaType, value)
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+		return bytes.NewReader(data), mediaType, nil
+	}
+}
+
+func encodeRawBody(mediaType string, value interface{}) (io.Reader, string, error) {
+	switch value := value.(type) {
+	case string:
+		return strings.NewReader(value), mediaType, nil
+	case json.RawMessage:
+		return bytes.NewReader(value), mediaType, nil
+	default:
+		return strings.NewReader(fmt.Sprint(value)), mediaType, nil
+	}
+}
+
+func toFormValues(value interface{}) (url.Values, error) {
+	result := url.Values{}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("form encoded request body must be an object, got: %T", value)
+	}
+	for k, v := range m {
+		switch v := v.(type) {
+		case nil:
+		case []interface{}:
+			for _, item := range v {
+				s, err := formValue(item)
+				if err != nil {
+					return nil, err
+				}
+				result.Add(k, s)
+			}
+		default:
+			s, err := formValue(v)
+			if err != nil {
+				return nil, err
+			}
+			result.Set(k, s)
+		}
+	}
+	return result, nil
+}
+
+func formValue(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case map[string]interface{}, []interface{}, json.RawMessage:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		return string(data), nil
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
+
+func encodeMultipartBody(content *openapi3.MediaType, value interface{}) (io.Reader, string, error) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, "", errors.Errorf("multipart request body must be an object, got: %T", value)
+	}
+
+	// Write the parts in a stable order.
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Stream the parts so that uploaded files don't get buffered in memory.
+	reader, pipeWriter := io.Pipe()
+	writer := multipart.NewWriter(pipeWriter)
+	go func() {
+		for _, name := range names {
+			v := m[name]
+			if v == nil {
+				continue
+			}
+
+			var items []interface{}
+			if list, ok := v.([]interface{}); ok && partContentType(content, name, v) != "application/json" {
+				// arrays of primitives and files are sent as repeated parts.
+				items = list
+			} else {
+				items = []interface{}{v}
+			}
+			for _, item := range items {
+				err := writeMultipartPart(writer, content, name, item)
+				if err != nil {
+					_ = pipeWriter.CloseWithError(err)
+					return
+				}
+			}
+		}
+		_ = pipeWriter.CloseWithError(writer.Close())
+	}()
+	return reader, writer.FormDataContentType(), nil
+}
+
+func writeMultipartPart(writer *multipart.Writer, content *openapi3.MediaType, name string, value interface{}) error {
+	contentType := partContentType(content, name, value)
+	header := textproto.MIMEHeader{}
+	if upload, ok := value.(*Upload); ok {
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(name), escapeQuotes(upload.Filename())))
+	} else {
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(name)))
+	}
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if upload, ok := value.(*Upload); ok {
+		_, err = io.Copy(part, upload)
+		return errors.WithStack(err)
+	}
+
+	var data []byte
+	if isJSONMediaType(contentType) {
+		data, err = json.Marshal(value)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	} else {
+		s, err := formValue(value)
+		if err != nil {
+			return err
+		}
+		data = []byte(s)
+	}
+	_, err = part.Write(data)
+	return errors.WithStack(err)
+}
+
+// partContentType determines the Content-Type of a multipart part using the encoding object
+// of the property if present, or the defaults defined by the OpenAPI spec otherwise.
+func partContentType(content *openapi3.MediaType, name string, value interface{}) string {
+	if content != nil {
+		if encoding := content.Encoding[name]; encoding != nil && encoding.ContentType != "" {
+			// the contentType can be a comma separated list, use the first one.
+			return strings.TrimSpace(strings.Split(encoding.ContentType, ",")[0])
+		}
+	}
+	switch value := value.(type) {
+	case *Upload:
+		return value.ContentType()
+	case map[string]interface{}, json.RawMessage:
+		return "application/json"
+	case []interface{}:
+		// arrays of objects or arrays are sent as a JSON part, the other arrays as repeated parts.
+		for _, item := range value {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}, json.RawMessage:
+				return "application/json"
+			}
+		}
+		return "text/plain"
+	default:
+		return "text/plain"
+	}
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
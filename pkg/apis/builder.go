@@ -3,6 +3,8 @@ package apis
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"log"
 	"net/http"
 	"net/url"
 	"reflect"
@@ -10,7 +12,6 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/chirino/graphql/inputconv"
 	"github.com/chirino/graphql/resolvers"
 	"github.com/chirino/graphql/schema"
 	"github.com/getkin/kin-openapi/openapi3"
@@ -18,16 +19,40 @@ import (
 )
 
 func NewResolverFactory(doc *openapi3.T, options Config) (resolvers.Resolver, string, error) {
+	// a first pass finds all the positions of the inline schemas, so that the type shared by identical
+	// inline schemas is named after their preferred position instead of the one mapped first.
+	first := options
+	first.Log = log.New(ioutil.Discard, "", 0)
+	first.Diagnostics = nil
+	names, _, _ := buildSchema(doc, first, nil)
+	builder, schemaText, err := buildSchema(doc, options, names.inlineNames)
+	if err != nil {
+		return nil, "", err
+	}
+	if options.WebhookReceiver != nil {
+		options.WebhookReceiver.replace(builder.webhooks, options.Webhooks)
+	}
+	return builder, schemaText, nil
+}
+
+// buildSchema maps the openapi document to a GraphQL schema.  sharedNames holds the names of the types
+// shared by identical inline schemas, by their cache key.
+func buildSchema(doc *openapi3.T, options Config, sharedNames map[string]inlineName) (*builder, string, error) {
 	builder := &builder{
 		draft:          schema.New(),
 		operationsById: map[string]*openapi3.Operation{},
 		refCache:       map[string]interface{}{},
+		inlineNames:    map[string]inlineName{},
+		sharedNames:    sharedNames,
+		webhooks:       map[string]*webhook{},
+		pollers:        newPollers(),
+		security:       doc.Security,
 		resolver: &resolver{
 			options: options,
 			//next:             resolvers.DynamicResolverFactory(),
 			resolvers:        make(map[string]resolvers.Resolver),
 			resultConverters: make(map[string]Converter),
-			inputConverters:  inputconv.TypeConverters{},
+			inputConverters:  typeConverters{},
 		},
 	}
 
@@ -38,7 +63,7 @@ func NewResolverFactory(doc *openapi3.T, options Config) (resolvers.Resolver, st
 				switch ss.Value.Type {
 				case "apiKey":
 					if options.APIBase.ApiKey == "" {
-						fmt.Println("API requires an api key, but it was not configured.")
+						builder.report(SeverityWarning, "/components/securitySchemes/"+escapePointerToken(ssName), "", "API requires an api key, but it was not configured.")
 						continue
 					}
 					switch ss.Value.In {
@@ -67,41 +92,116 @@ func NewResolverFactory(doc *openapi3.T, options Config) (resolvers.Resolver, st
 		}
 	}
 
+	builder.tags = map[string]*openapi3.Tag{}
+	for _, tag := range doc.Tags {
+		if tag != nil {
+			builder.tags[tag.Name] = tag
+		}
+	}
+
 	// Lets index all the operations.. needed later when looking up operation due to links.
-	for path, v := range doc.Paths {
-		for method, operation := range v.Operations() {
+	for _, path := range sortedPaths(doc.Paths) {
+		operations := doc.Paths[path].Operations()
+		for _, method := range sortedMethods(operations) {
+			operation := operations[method]
+			if operation.Extensions == nil {
+				operation.Extensions = map[string]interface{}{}
+			}
+			operation.Extensions["path"] = path
+			operation.Extensions["method"] = method
+			// a configured api URL is used for all the operations, it can be a proxy or a test server.
+			if server := operationServer(doc.Paths[path], operation, options.Server); server != nil && options.documentServers {
+				serverURL, err := ServerURL(server, options.Server.Variables, serverBase(options))
+				if err != nil {
+					builder.report(SeverityError, operationPointer(operation), "", "could not use the server of the operation: %s", err)
+				} else {
+					operation.Extensions["server"] = serverURL
+				}
+			}
 			if operation.OperationID != "" {
 				if builder.operationsById[operation.OperationID] != nil {
-					// error?
-					fmt.Println("Duplicate operation id found:", operation.OperationID)
+					builder.report(SeverityWarning, operationPointer(operation), "", "Duplicate operation id found: %s", operation.OperationID)
 				}
-				if operation.Extensions == nil {
-					operation.Extensions = map[string]interface{}{}
-				}
-				operation.Extensions["path"] = path
-				operation.Extensions["method"] = method
 				builder.operationsById[operation.OperationID] = operation
 			}
 		}
 	}
 
+	// Map the operations in a stable order so that the generated type names do not depend on
+	// the random map iteration order.
 	queryMethods := map[string]bool{"GET": true, "HEAD": true}
-	for path, v := range doc.Paths {
-		for method, operation := range v.Operations() {
+	for _, path := range sortedPaths(doc.Paths) {
+		operations := doc.Paths[path].Operations()
+		for _, method := range sortedMethods(operations) {
+			operation := operations[method]
+			pointer := operationPointer(operation)
+			fieldName := builder.operationFieldName(operation)
+			stream, err := builder.streamingResponse(operation)
+			if err != nil {
+				builder.report(SeverityError, pointer, options.SubscriptionType+"."+fieldName, "could not map api endpoint '%s %s' to a subscription: %s", method, path, err)
+			} else if stream != nil {
+				err := builder.addOperationField(options.SubscriptionType, operation, stream)
+				if err != nil {
+					builder.report(SeverityError, pointer, options.SubscriptionType+"."+fieldName, "could not map api endpoint '%s %s' to a subscription: %s", method, path, err)
+				}
+				if stream.only {
+					continue
+				}
+			}
+
 			if queryMethods[method] {
 				err := builder.addRootField(options.QueryType, operation)
 				if err != nil {
-					builder.options.Log.Printf("could not map api endpoint '%s %s': %s", method, path, err)
+					builder.report(SeverityError, pointer, options.QueryType+"."+fieldName, "could not map api endpoint '%s %s': %s", method, path, err)
+					continue
+				}
+				if method != "GET" {
+					continue
+				}
+				interval, poll, err := builder.pollInterval(operation)
+				if poll && stream != nil {
+					builder.report(SeverityInfo, pointer, options.SubscriptionType+"."+fieldName, "not polling api endpoint '%s %s': it streams its response", method, path)
+					continue
+				}
+				if err != nil {
+					builder.report(SeverityError, pointer, options.SubscriptionType+"."+fieldName, "could not map api endpoint '%s %s' to a subscription: %s", method, path, err)
+				} else if poll {
+					err = builder.addSubscriptionField(operation, interval)
+					if err != nil {
+						builder.report(SeverityError, pointer, options.SubscriptionType+"."+fieldName, "could not map api endpoint '%s %s' to a subscription: %s", method, path, err)
+					}
 				}
 			} else {
 				err := builder.addRootField(options.MutationType, operation)
 				if err != nil {
-					builder.options.Log.Printf("could not map api endpoint '%s %s': %s", method, path, err)
+					builder.report(SeverityError, pointer, options.MutationType+"."+fieldName, "could not map api endpoint '%s %s': %s", method, path, err)
 				}
 			}
 		}
 	}
 
+	if options.WebhookReceiver != nil {
+		for _, path := range sortedPaths(doc.Paths) {
+			operations := doc.Paths[path].Operations()
+			for _, method := range sortedMethods(operations) {
+				builder.addCallbackFields(operations[method])
+			}
+		}
+		if webhooks, ok := doc.Extensions[webhooksExtension].(openapi3.Paths); ok {
+			var names []string
+			for name := range webhooks {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				builder.addWebhookFields(builder.options.fieldName(name), "/"+webhooksExtension+"/"+escapePointerToken(name), webhooks[name])
+			}
+		}
+	}
+
+	builder.removeEmptyNamespaces(options.QueryType)
+	builder.removeEmptyNamespaces(options.MutationType)
+
 	// Sort the type fields since we generated them by mutating..
 	// which leads to then being in a random order based on the random order
 	// they are received from the openapi doc.
@@ -125,6 +225,9 @@ func NewResolverFactory(doc *openapi3.T, options Config) (resolvers.Resolver, st
 	if draft.Types[options.QueryType] != nil {
 		draft.EntryPoints[schema.Query] = draft.Types[options.QueryType]
 	}
+	if draft.Types[options.SubscriptionType] != nil {
+		draft.EntryPoints[schema.Subscription] = draft.Types[options.SubscriptionType]
+	}
 
 	if draft.Types["JSON"] != nil {
 		builder.inputConverters["JSON"] = func(t schema.Type, value interface{}) (interface{}, error) {
@@ -136,27 +239,41 @@ func NewResolverFactory(doc *openapi3.T, options Config) (resolvers.Resolver, st
 			}
 		}
 		builder.resultConverters["JSON"] = func(value reflect.Value, err error) (reflect.Value, error) {
-			// input is an object, convert to a string
+			// input is any json value, convert to a string
 			if err != nil {
 				return value, err
 			}
-			if value.IsNil() {
-				return value, err
+			for value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr {
+				if value.IsNil() {
+					return value, err
+				}
+				value = value.Elem()
 			}
-			m := value.Interface().(map[string]interface{})
-			if m == nil {
+			if !value.IsValid() || (value.Kind() == reflect.Map || value.Kind() == reflect.Slice) && value.IsNil() {
 				return value, err
 			}
-			d, err := json.Marshal(m)
+			d, err := json.Marshal(value.Interface())
 			if err != nil {
 				return value, err
 			}
 			return reflect.ValueOf(string(d)), nil
 		}
 	}
+	if draft.Types["Upload"] != nil {
+		builder.inputConverters["Upload"] = func(t schema.Type, value interface{}) (interface{}, error) {
+			switch value := value.(type) {
+			case nil:
+				return nil, nil
+			case *Upload:
+				return value, nil
+			default:
+				return nil, errors.New("expected a file sent using a GraphQL multipart request for the Upload scalar")
+			}
+		}
+	}
 	err := draft.ResolveTypes()
 	if err != nil {
-		return nil, "", err
+		return builder, "", err
 	}
 	return builder, draft.String(), nil
 }
@@ -166,43 +283,79 @@ type builder struct {
 	draft          *schema.Schema
 	operationsById map[string]*openapi3.Operation
 	refCache       map[string]interface{}
+	// inlineNames collects the preferred name of the inline schemas, by their cache key.
+	inlineNames map[string]inlineName
+	// sharedNames holds the names collected by the first pass, they name the types of the inline schemas.
+	sharedNames map[string]inlineName
+	pollers     *pollers
+	tags        map[string]*openapi3.Tag
+	// webhooks are handed to the WebhookReceiver once the schema is built.
+	webhooks map[string]*webhook
+	// security is the default security requirements of the operations.
+	security openapi3.SecurityRequirements
+	// pointer is the JSON pointer of the schema being mapped, used in the reported diagnostics.
+	pointer string
 }
 
 var _ resolvers.Resolver = &resolver{}
 
 func (builder builder) addRootField(rootType string, operation *openapi3.Operation) error {
+	return builder.addOperationField(rootType, operation, nil)
+}
+
+// addOperationField adds a field that calls the operation to the root type.  If stream is not nil,
+// the field is a subscription that fires an event for every event in the streamed response.
+func (builder builder) addOperationField(rootType string, operation *openapi3.Operation, stream *streamContent) error {
 
 	draft := builder.draft
 
+	// the field goes into the namespace object of the operation when grouping by tags.
+	objectType := rootType
+	if stream == nil {
+		objectType = builder.namespaceType(rootType, operation)
+	}
+
 	var rootObject *schema.Object
-	if t, ok := draft.Types[rootType]; ok {
+	if t, ok := draft.Types[objectType]; ok {
 		rootObject = t.(*schema.Object)
 	} else {
 		rootObject = &schema.Object{
-			Name: rootType,
+			Name: objectType,
 		}
-		draft.Types[rootType] = rootObject
+		draft.Types[objectType] = rootObject
 	}
 
 	path := operation.Extensions["path"].(string)
 	method := operation.Extensions["method"].(string)
-
-	fieldName := sanitizeName(path)
-	if operation.OperationID != "" {
-		fieldName = sanitizeName(operation.OperationID)
-	}
+	fieldName := builder.operationFieldName(operation)
+	pointer := operationPointer(operation)
+	fieldPath := objectType + "." + fieldName
 
 	if rootObject.Fields.Get(fieldName) != nil {
-		builder.options.Log.Printf("field already exists: %s", fieldName)
+		builder.report(SeverityError, pointer, fieldPath, "field already exists: %s", fieldName)
 		return nil
 	}
 
 	typePath := rootType + capitalizeFirstLetter(fieldName)
 
-	qlType, status, err := builder.getOperationResponseType(operation, rootType, fieldName, typePath)
-	if err != nil {
-		builder.options.Log.Println(err.Error())
-		return nil
+	var qlType schema.Type
+	var status []int
+	var err error
+	if stream != nil {
+		status = stream.status
+		restore := builder.at(pointer + "/responses")
+		qlType, err = builder.getStreamEventType(stream, typePath)
+		restore()
+		if err != nil {
+			builder.report(SeverityError, pointer+"/responses", fieldPath, "dropping %s.%s field: event type cannot be converted: %s", rootType, fieldName, err)
+			return nil
+		}
+	} else {
+		qlType, status, err = builder.getOperationResponseType(operation, rootType, fieldName, typePath)
+		if err != nil {
+			builder.report(SeverityError, pointer+"/responses", fieldPath, "%s", err.Error())
+			return nil
+		}
 	}
 
 	text := ""
@@ -214,87 +367,143 @@ func (builder builder) addRootField(rootType string, operation *openapi3.Operati
 	}
 	text = text + "\n**endpoint:** `" + method + " " + path + "`"
 	field := &schema.Field{
-		Name: fieldName,
-		Desc: desc(text),
-		Type: qlType,
+		Name:       fieldName,
+		Desc:       desc(text),
+		Type:       qlType,
+		Directives: deprecatedDirectives(operation.Deprecated, operation.Extensions),
 	}
 
 	argNames := map[string]bool{}
-	if operation.RequestBody != nil {
-		content := operation.RequestBody.Value.Content.Get("application/json")
-		if content != nil {
-			fieldType, err := builder.addGraphQLType(content.Schema, typePath+"/body", true)
+	// operations with an empty request body content are mapped without a body argument.
+	if mediaType, content := requestBodyContent(operation); content != nil {
+		if isRawMediaType(mediaType) {
+			builder.report(SeverityInfo, pointer+"/requestBody/content/"+escapePointerToken(mediaType), fieldPath+"(body)", "request body media type %s is sent as a String", mediaType)
+		}
+
+		var fieldType schema.Type = draft.Types["String"]
+		if content.Schema != nil {
+			schemaPointer := pointer + "/requestBody/content/" + escapePointerToken(mediaType) + "/schema"
+			restore := builder.at(schemaPointer)
+			fieldType, err = builder.addGraphQLType(content.Schema, typePath+"/body", true)
+			restore()
 			if err != nil {
-				builder.options.Log.Printf("dropping %s.%s field: required parameter '%s' type cannot be converted: %s", rootType, fieldName, "body", err)
+				builder.report(SeverityError, schemaPointer, fieldPath+"(body)", "dropping %s.%s field: required parameter '%s' type cannot be converted: %s", rootType, fieldName, "body", err)
 				return nil
 			}
-
-			argName := makeUnique(argNames, "body")
-			field.Args = append(field.Args, &schema.InputValue{
-				Name: argName,
-				Type: requiredType(fieldType, true),
-			})
+		} else if mediaType != "text/plain" && !isRawMediaType(mediaType) {
+			fieldType = builder.JSONType()
 		}
+
+		argName := makeUnique(argNames, "body")
+		field.Args = append(field.Args, &schema.InputValue{
+			Name: argName,
+			Type: requiredType(fieldType, true),
+		})
 	}
 
 	if len(operation.Parameters) > 0 {
+		// parameters can have the same name in different locations, their types are then named after both.
+		paramNames := map[string]int{}
+		for _, param := range operation.Parameters {
+			paramNames[param.Value.Name]++
+		}
 		for i, param := range operation.Parameters {
+			paramPointer := fmt.Sprintf("%s/parameters/%d", pointer, i)
+			if param.Ref != "" {
+				paramPointer = schemaPointer(&openapi3.SchemaRef{Ref: param.Ref}, paramPointer)
+			}
 
 			if param.Value.In == "header" && param.Value.Name == "Accept-Encoding" {
 				// the go http client automatically handles gzip decoding...
 				// don't allow setting the Accept-Encoding header via a parameter.
 				continue
 			}
+			if isWatchParam(stream, param.Value) {
+				// the subscription always watches.
+				continue
+			}
 
-			argName := makeUnique(argNames, sanitizeName(param.Value.Name))
-			fieldType, err := builder.addGraphQLType(getSchema(param.Value), fmt.Sprintf("%sArg%d", typePath, i), true)
+			argName := makeUnique(argNames, builder.options.fieldName(param.Value.Name))
+			// name the argument types after the parameter so they don't change when parameters are reordered.
+			argTypePath := typePath + "Arg" + capitalizeFirstLetter(param.Value.Name)
+			if paramNames[param.Value.Name] > 1 {
+				argTypePath = typePath + "Arg" + capitalizeFirstLetter(param.Value.In) + capitalizeFirstLetter(param.Value.Name)
+			}
+			restore := builder.at(paramPointer + "/schema")
+			fieldType, err := builder.addGraphQLType(getSchema(param.Value), argTypePath, true)
+			restore()
 			if err != nil {
 				if param.Value.Required {
-					builder.options.Log.Printf("dropping %s.%s field: required parameter '%s' type cannot be converted: %s", rootType, fieldName, param.Value.Name, err)
+					builder.report(SeverityError, paramPointer, fieldPath+"("+argName+")", "dropping %s.%s field: required parameter '%s' type cannot be converted: %s", rootType, fieldName, param.Value.Name, err)
 					return nil
 				} else {
-					builder.options.Log.Printf("dropping optional %s.%s field parameter: parameter '%s' type cannot be converted: %s", rootType, fieldName, param.Value.Name, err)
+					builder.report(SeverityWarning, paramPointer, fieldPath+"("+argName+")", "dropping optional %s.%s field parameter: parameter '%s' type cannot be converted: %s", rootType, fieldName, param.Value.Name, err)
 					continue
 				}
 			}
 
-			field.Args = append(field.Args, &schema.InputValue{
-				Desc: desc(param.Value.Description),
+			arg := &schema.InputValue{
+				Desc: desc(annotate(param.Value.Description, parameterExample(param.Value), param.Value.Deprecated, param.Value.Extensions) + constAnnotation(getSchema(param.Value))),
 				Name: argName,
 				Type: requiredType(fieldType, param.Value.Required),
-			})
+			}
+			if s := getSchema(param.Value); s.Value.Default != nil {
+				arg.Default, err = builder.defaultLiteral(arg.Type, s.Value.Default)
+				if err != nil {
+					builder.report(SeverityWarning, paramPointer+"/schema/default", fieldPath+"("+argName+")", "dropping %s.%s field parameter '%s' default value: %s", rootType, fieldName, param.Value.Name, err)
+				}
+			}
+			field.Args = append(field.Args, arg)
 		}
 	}
 
 	rootObject.Fields = append(rootObject.Fields, field)
-	builder.resolvers[rootType+":"+fieldName] = resolvers.Func(func(request *resolvers.ResolveRequest, next resolvers.Resolution) resolvers.Resolution {
+	if stream != nil {
+		builder.resolvers[objectType+":"+fieldName] = &streamResolver{
+			resolver:  builder.resolver,
+			operation: operation,
+			stream:    stream,
+		}
+		return nil
+	}
+	builder.resolvers[objectType+":"+fieldName] = resolvers.Func(func(request *resolvers.ResolveRequest, next resolvers.Resolution) resolvers.Resolution {
 		return builder.resolve(request, operation, status)
 	})
 
 	return nil
 }
 
+func (builder *builder) operationFieldName(operation *openapi3.Operation) string {
+	if operation.OperationID != "" {
+		return builder.options.fieldName(operation.OperationID)
+	}
+	return builder.options.fieldName(operation.Extensions["path"].(string))
+}
+
 func (builder *builder) getOperationResponseType(operation *openapi3.Operation, rootType string, fieldName string, typePath string) (schema.Type, []int, error) {
 
 	responseTypesToStatus := map[schema.Type][]int{}
-	for statusText, response := range operation.Responses {
+	var statusTexts []string
+	for statusText := range operation.Responses {
+		statusTexts = append(statusTexts, statusText)
+	}
+	sort.Strings(statusTexts)
+	for _, statusText := range statusTexts {
+		response := operation.Responses[statusText]
 		status, err := strconv.Atoi(statusText)
 		if err != nil {
-			builder.options.Log.Printf("skipping %s.%s field response, not an integer: %s", rootType, fieldName, statusText)
+			builder.report(SeverityInfo, operationPointer(operation)+"/responses/"+escapePointerToken(statusText), rootType+"."+fieldName, "skipping %s.%s field response, not an integer: %s", rootType, fieldName, statusText)
 		}
 		if strings.HasPrefix(statusText, "2") {
 			var qlType schema.Type = nil
 			if response.Value.Content == nil {
 				qlType = builder.NoContentType()
 			} else {
-
-				content := response.Value.Content.Get("application/json")
-				if content != nil {
-
-					qlType, err = builder.addGraphQLType(content.Schema, typePath, false)
-					if err != nil {
-						return nil, nil, errors.Errorf("dropping %s.%s field: result type cannot be converted: %s", rootType, fieldName, err)
-					}
+				restore := builder.at(operationPointer(operation) + "/responses/" + escapePointerToken(statusText))
+				qlType, err = builder.getResponseContentType(operation, response.Value.Content, typePath)
+				restore()
+				if err != nil {
+					return nil, nil, errors.Errorf("dropping %s.%s field: result type cannot be converted: %s", rootType, fieldName, err)
 				}
 			}
 
@@ -320,6 +529,32 @@ func (builder *builder) getOperationResponseType(operation *openapi3.Operation,
 	return nil, nil, errors.Errorf("dropping %s.%s field: graphql multiple result types not yet supported", rootType, fieldName)
 }
 
+func (builder *builder) getResponseContentType(operation *openapi3.Operation, content openapi3.Content, typePath string) (schema.Type, error) {
+	mediaType, mt, format := builder.responseContent(content)
+	if mediaType == "" {
+		return nil, nil
+	}
+	switch format {
+	case textResponse:
+		return builder.draft.Types["String"], nil
+	case binaryResponse:
+		if builder.downloadable(operation) {
+			operation.Extensions["download"] = true
+			return builder.draft.Types["String"], nil
+		}
+		if builder.options.BinaryResponses == BinaryResponsesURL && operation.Extensions["method"] == "GET" {
+			builder.report(SeverityInfo, operationPointer(operation), "", "binary response returned as Base64 instead of a download URL: the operation needs credentials")
+		}
+		return builder.Base64Type(), nil
+	default:
+		if mt.Schema == nil {
+			return builder.JSONType(), nil
+		}
+		defer builder.at(builder.pointer + "/content/" + escapePointerToken(mediaType) + "/schema")()
+		return builder.addGraphQLType(mt.Schema, typePath, false)
+	}
+}
+
 func getSchema(value *openapi3.Parameter) *openapi3.SchemaRef {
 	if value.Schema != nil {
 		return value.Schema
@@ -338,11 +573,14 @@ func (builder *builder) addGraphQLType(sf *openapi3.SchemaRef, path string, inpu
 	if sf == nil || sf.Value == nil {
 		panic("a schema reference was not resolved.")
 	}
-	cacheKey := "o:" + sf.Ref
-	if inputType {
-		cacheKey = "i:" + sf.Ref
+	cacheKey := typeCacheKey(sf, inputType)
+	if cacheKey != "" && sf.Ref == "" {
+		builder.addInlineName(cacheKey, path)
+		if shared, ok := builder.sharedNames[cacheKey]; ok {
+			path = shared.path
+		}
 	}
-	if sf.Ref != "" {
+	if cacheKey != "" {
 		if v, ok := refCache[cacheKey]; ok {
 			if v, ok := v.(schema.Type); ok {
 				return v, nil
@@ -352,22 +590,21 @@ func (builder *builder) addGraphQLType(sf *openapi3.SchemaRef, path string, inpu
 	}
 
 	r, err := builder._addGraphQLType(sf, path, inputType)
-	if err != nil {
-		refCache[cacheKey] = err
-		return nil, err
+	if cacheKey != "" {
+		if err != nil {
+			refCache[cacheKey] = err
+		} else {
+			refCache[cacheKey] = r
+		}
 	}
-	refCache[cacheKey] = r
-	return r, nil
+	return r, err
 }
 
 func (builder *builder) _addGraphQLType(sf *openapi3.SchemaRef, pathBasedTypeName string, inputType bool) (schema.Type, error) {
 	draft := builder.draft
+	defer builder.at(schemaPointer(sf, builder.pointer))()
 
-	typeName := pathBasedTypeName
-	if sf.Ref != "" {
-		typeName = strings.TrimPrefix(sf.Ref, "#/components/schemas/")
-	}
-	typeName = sanitizeName(typeName)
+	typeName := builder.typeName(sf, pathBasedTypeName)
 	pathBasedTypeName = typeName
 	if inputType {
 		typeName += "Input"
@@ -377,6 +614,12 @@ func (builder *builder) _addGraphQLType(sf *openapi3.SchemaRef, pathBasedTypeNam
 
 	switch sf.Value.Type {
 	case "string":
+		if inputType && sf.Value.Format == "binary" {
+			return builder.UploadType(), nil
+		}
+		if name, value, ok := constEnumValue(sf.Value); ok {
+			return builder.ConstEnumType(pathBasedTypeName+"Enum", name, value), nil
+		}
 		return draft.Types["String"], nil
 	case "integer":
 		return draft.Types["Int"], nil
@@ -385,7 +628,9 @@ func (builder *builder) _addGraphQLType(sf *openapi3.SchemaRef, pathBasedTypeNam
 	case "boolean":
 		return draft.Types["Boolean"], nil
 	case "array":
+		restore := builder.at(builder.pointer + "/items")
 		nestedType, err := builder.addGraphQLType(sf.Value.Items, pathBasedTypeName, inputType)
+		restore()
 		if err != nil {
 			return nil, err
 		}
@@ -398,7 +643,9 @@ func (builder *builder) _addGraphQLType(sf *openapi3.SchemaRef, pathBasedTypeNam
 
 			// We can use a property wrapper if know the type of the values
 			if sf.Value.AdditionalProperties != nil {
+				restore := builder.at(builder.pointer + "/additionalProperties")
 				nestedType, err := builder.addGraphQLType(sf.Value.AdditionalProperties, pathBasedTypeName, inputType)
+				restore()
 				if err != nil {
 					return nil, err
 				}
@@ -410,23 +657,8 @@ func (builder *builder) _addGraphQLType(sf *openapi3.SchemaRef, pathBasedTypeNam
 			}
 
 			// I think it's safe to assume additional properties are allowed, if the object has no type
-			t, found := draft.Types["JSON"]
-			if !found {
-				t = &schema.Scalar{
-					Name: "JSON",
-					Desc: desc("a JSON encoded object"),
-				}
-				draft.Types["JSON"] = t
-			}
-			return t, nil
+			return builder.JSONType(), nil
 
-		} else {
-			if sf.Value.AdditionalProperties != nil {
-				return nil, errors.New(fmt.Sprintf("cannot support additional prperties on graphql type '%s'", typeName))
-			}
-			if sf.Value.AdditionalPropertiesAllowed != nil && *sf.Value.AdditionalPropertiesAllowed {
-				return nil, errors.New(fmt.Sprintf("cannot support additional prperties on graphql type '%s'", typeName))
-			}
 		}
 
 		t := draft.Types[typeName]
@@ -448,7 +680,15 @@ func (builder *builder) _addGraphQLType(sf *openapi3.SchemaRef, pathBasedTypeNam
 		// In case a type is recursive.. lets stick it in the cache now before we try to resolve it's fields..
 		draft.Types[typeName] = t
 
-		builder.addProperties(sf.Value, pathBasedTypeName, inputType, typeName, t)
+		builder.addProperties(sf.Value, pathBasedTypeName, inputType, typeName, t, map[string]bool{})
+
+		// Objects that also allow additional properties get an extra field to hold them.
+		if sf.Value.AdditionalProperties != nil || sf.Value.AdditionalPropertiesAllowed != nil && *sf.Value.AdditionalPropertiesAllowed {
+			err := builder.addAdditionalPropertiesField(sf.Value, pathBasedTypeName, inputType, typeName, t)
+			if err != nil {
+				builder.report(SeverityWarning, builder.pointer+"/additionalProperties", typeName+"."+additionalPropertiesField, "dropping additional properties of graphql type '%s': %s", typeName, err)
+			}
+		}
 
 		if inputType {
 			object := t.(*schema.InputObject)
@@ -497,34 +737,93 @@ func hasProperties(value *openapi3.Schema, visited map[*openapi3.Schema]bool) bo
 	return false
 }
 
-func (builder *builder) addProperties(s *openapi3.Schema, pathBasedTypeName string, inputType bool, typeName string, graphqlType interface{}) {
-	for _, sf := range s.AllOf {
-		builder.addProperties(sf.Value, pathBasedTypeName, inputType, typeName, graphqlType)
+// allOfRequired lists the names required by the schema and by the members of its allOf.
+func allOfRequired(s *openapi3.Schema, visited map[*openapi3.Schema]bool) []string {
+	if s == nil || visited[s] {
+		return nil
+	}
+	visited[s] = true
+	result := append([]string{}, s.Required...)
+	for _, ref := range s.AllOf {
+		result = append(result, allOfRequired(ref.Value, visited)...)
+	}
+	return result
+}
+
+// addProperties adds the properties of the schema to the graphql type.  required holds the names
+// of the properties required by the enclosing schemas, it is nil for the properties of oneOf/anyOf
+// alternatives since those are never required.
+func (builder *builder) addProperties(s *openapi3.Schema, pathBasedTypeName string, inputType bool, typeName string, graphqlType interface{}, required map[string]bool) {
+	if required != nil {
+		merged := map[string]bool{}
+		for name := range required {
+			merged[name] = true
+		}
+		// the members of an allOf can require the properties declared by their siblings.
+		for _, name := range allOfRequired(s, map[*openapi3.Schema]bool{}) {
+			merged[name] = true
+		}
+		required = merged
+	}
+	pointer := builder.pointer
+	for i, sf := range s.AllOf {
+		restore := builder.at(schemaPointer(sf, fmt.Sprintf("%s/allOf/%d", pointer, i)))
+		builder.addProperties(sf.Value, pathBasedTypeName, inputType, typeName, graphqlType, required)
+		restore()
 	}
-	for _, sf := range s.AnyOf {
-		builder.addProperties(sf.Value, pathBasedTypeName, inputType, typeName, graphqlType)
+	for i, sf := range s.AnyOf {
+		restore := builder.at(schemaPointer(sf, fmt.Sprintf("%s/anyOf/%d", pointer, i)))
+		builder.addProperties(sf.Value, pathBasedTypeName, inputType, typeName, graphqlType, nil)
+		restore()
 	}
-	for _, sf := range s.OneOf {
-		builder.addProperties(sf.Value, pathBasedTypeName, inputType, typeName, graphqlType)
+	for i, sf := range s.OneOf {
+		restore := builder.at(schemaPointer(sf, fmt.Sprintf("%s/oneOf/%d", pointer, i)))
+		builder.addProperties(sf.Value, pathBasedTypeName, inputType, typeName, graphqlType, nil)
+		restore()
 	}
-	for name, ref := range s.Properties {
+	wireNames := map[string]string{}
+	for _, name := range sortedSchemaNames(s.Properties) {
+		ref := s.Properties[name]
+		// readOnly properties are only sent by the API, and writeOnly ones only sent to it.
+		if inputType && ref.Value.ReadOnly || !inputType && ref.Value.WriteOnly {
+			continue
+		}
+		propPointer := pointer + "/properties/" + escapePointerToken(name)
+		restore := builder.at(propPointer)
 		fieldType, err := builder.addGraphQLType(ref, pathBasedTypeName+capitalizeFirstLetter(name), inputType)
+		restore()
 		if err != nil {
-			builder.options.Log.Printf("dropping openapi field '%s' from graphql type '%s': %s", name, typeName, err)
+			builder.report(SeverityWarning, propPointer, typeName+"."+builder.options.fieldName(name), "dropping openapi field '%s' from graphql type '%s': %s", name, typeName, err)
 			continue
 		}
-		fieldName := sanitizeName(name)
+		if required[name] && !ref.Value.Nullable && (inputType || builder.options.RequiredResults) {
+			fieldType = &schema.NonNull{OfType: fieldType}
+		}
+		fieldName := builder.options.fieldName(name)
+		if fieldName != name {
+			if inputType {
+				wireNames[fieldName] = name
+			} else {
+				builder.resolvers[typeName+":"+fieldName] = wireNameResolver(name)
+			}
+		}
 		if inputType {
 			object := graphqlType.(*schema.InputObject)
 			newField := &schema.InputValue{
-				Desc: desc(ref.Value.Description),
+				Desc: desc(annotate(ref.Value.Description, ref.Value.Example, ref.Value.Deprecated, ref.Value.Extensions) + constAnnotation(ref)),
 				Name: fieldName,
 				Type: fieldType,
 			}
+			if ref.Value.Default != nil {
+				newField.Default, err = builder.defaultLiteral(fieldType, ref.Value.Default)
+				if err != nil {
+					builder.report(SeverityWarning, propPointer+"/default", typeName+"."+fieldName, "dropping openapi field '%s' default value from graphql type '%s': %s", name, typeName, err)
+				}
+			}
 			existingField := object.Fields.Get(fieldName)
 			if existingField != nil {
 				if !reflect.DeepEqual(newField.Type, existingField.Type) {
-					builder.options.Log.Printf("field type conflict '%s.%s'", object.Name, fieldName)
+					builder.report(SeverityWarning, propPointer, object.Name+"."+fieldName, "field type conflict '%s.%s'", object.Name, fieldName)
 				}
 			} else {
 				object.Fields = append(object.Fields, newField)
@@ -532,20 +831,24 @@ func (builder *builder) addProperties(s *openapi3.Schema, pathBasedTypeName stri
 		} else {
 			object := graphqlType.(*schema.Object)
 			newField := &schema.Field{
-				Desc: desc(ref.Value.Description),
-				Name: fieldName,
-				Type: fieldType,
+				Desc:       desc(annotate(ref.Value.Description, ref.Value.Example, false, nil) + constAnnotation(ref)),
+				Name:       fieldName,
+				Type:       fieldType,
+				Directives: deprecatedDirectives(ref.Value.Deprecated, ref.Value.Extensions),
 			}
 			existingField := object.Fields.Get(fieldName)
 			if existingField != nil {
 				if !reflect.DeepEqual(newField.Type, existingField.Type) {
-					builder.options.Log.Printf("field type conflict '%s.%s'", object.Name, fieldName)
+					builder.report(SeverityWarning, propPointer, object.Name+"."+fieldName, "field type conflict '%s.%s'", object.Name, fieldName)
 				}
 			} else {
 				object.Fields = append(object.Fields, newField)
 			}
 		}
 	}
+	if len(wireNames) > 0 {
+		builder.addInputConverter(typeName, wireNamesConverter(wireNames))
+	}
 	if !inputType {
 		object := graphqlType.(*schema.Object)
 		links := s.Extensions["x-links"]
@@ -553,12 +856,18 @@ func (builder *builder) addProperties(s *openapi3.Schema, pathBasedTypeName stri
 			links := openapi3.Links{}
 			err := json.Unmarshal(linksRaw, &links)
 			if err != nil {
-				builder.options.Log.Printf("invalid x-links extension: %s", string(linksRaw))
+				builder.report(SeverityWarning, pointer+"/x-links", typeName, "invalid x-links extension: %s", string(linksRaw))
 			} else {
-				for field, link := range links {
+				var fields []string
+				for field := range links {
+					fields = append(fields, field)
+				}
+				sort.Strings(fields)
+				for _, field := range fields {
+					link := links[field]
 					err := builder.addLink(object, field, pathBasedTypeName+capitalizeFirstLetter(field), link.Value)
 					if err != nil {
-						builder.options.Log.Printf("dropping x-links field '%s.%s': %v", typeName, field, err)
+						builder.report(SeverityWarning, pointer+"/x-links/"+escapePointerToken(field), typeName+"."+field, "dropping x-links field '%s.%s': %v", typeName, field, err)
 					}
 				}
 			}
@@ -610,10 +919,10 @@ func (builder *builder) addLink(qlType *schema.Object, fieldName string, typePat
 
 func (builder *builder) addPropWrapper(draft *schema.Schema, nestedType schema.Type, inputType bool) (schema.NamedType, error) {
 
-	nestedTypeLong := schema.DeepestType(nestedType).String()
-	graphTypeName := nestedTypeLong + "ResultProp"
+	valueTypeName := propValueTypeName(nestedType)
+	graphTypeName := valueTypeName + "ResultProp"
 	if inputType {
-		graphTypeName = nestedTypeLong + "InputProp"
+		graphTypeName = valueTypeName + "InputProp"
 	}
 
 	// Wrapper type might already exist...
@@ -727,3 +1036,42 @@ func (builder *builder) NoContentType() schema.Type {
 	}
 	return t
 }
+
+func (builder *builder) JSONType() schema.Type {
+	draft := builder.draft
+	t := draft.Types["JSON"]
+	if t == nil {
+		t = &schema.Scalar{
+			Name: "JSON",
+			Desc: desc("a JSON encoded object"),
+		}
+		draft.Types["JSON"] = t
+	}
+	return t
+}
+
+func (builder *builder) UploadType() schema.Type {
+	draft := builder.draft
+	t := draft.Types["Upload"]
+	if t == nil {
+		t = &schema.Scalar{
+			Name: "Upload",
+			Desc: desc("A file sent using the GraphQL multipart request spec"),
+		}
+		draft.Types["Upload"] = t
+	}
+	return t
+}
+
+func (builder *builder) Base64Type() schema.Type {
+	draft := builder.draft
+	t := draft.Types["Base64"]
+	if t == nil {
+		t = &schema.Scalar{
+			Name: "Base64",
+			Desc: desc("Base64 encoded binary data"),
+		}
+		draft.Types["Base64"] = t
+	}
+	return t
+}
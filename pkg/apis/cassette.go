@@ -0,0 +1,316 @@
+package apis
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// Interaction is an upstream request and its response, as saved in a cassette file.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest holds the parts of a request that are matched when replaying it.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	// Body is the request body, base64 encoded when BodyEncoding is "base64".  The random boundary of
+	// multipart bodies is replaced by a fixed one, so that uploads match when they are replayed.
+	Body         string `json:"body,omitempty"`
+	BodyEncoding string `json:"body-encoding,omitempty"`
+}
+
+// cassetteBoundary replaces the boundary of the recorded multipart bodies.
+const cassetteBoundary = "cassette-boundary"
+
+// RecordedResponse holds a response to play back.
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	// Body is the response body, base64 encoded when BodyEncoding is "base64".
+	Body         string `json:"body,omitempty"`
+	BodyEncoding string `json:"body-encoding,omitempty"`
+}
+
+// RecordingTransport is a http.RoundTripper that saves every request and response it forwards to the
+// Next transport as a cassette file in the Dir directory.  The cassette is written as soon as the response
+// headers are received, and written again with the response body once it was read to the end or closed,
+// so that streamed and aborted responses are recorded too.  Request bodies are buffered in memory to be
+// saved, recording is meant for test traffic rather than large uploads.
+type RecordingTransport struct {
+	Dir  string
+	Next http.RoundTripper
+
+	mu    sync.Mutex
+	count int
+}
+
+// NewRecordingTransport creates the cassette directory and returns a transport recording the traffic
+// of next, or of the http.DefaultTransport if nil.
+func NewRecordingTransport(dir string, next http.RoundTripper) (*RecordingTransport, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	files, err := cassetteFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	// keep the existing cassettes, new ones are numbered after them.
+	return &RecordingTransport{Dir: dir, Next: next, count: len(files)}, nil
+}
+
+func (t *RecordingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request, requestBody, err := readBody(request)
+	if err != nil {
+		return nil, err
+	}
+	response, err := t.Next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &Interaction{
+		Request: recordedRequest(request, requestBody),
+		Response: RecordedResponse{
+			Status: response.StatusCode,
+			Header: response.Header,
+		},
+	}
+	t.mu.Lock()
+	t.count++
+	file := filepath.Join(t.Dir, fmt.Sprintf("%04d-%s.yaml", t.count, strings.ToLower(request.Method)))
+	t.mu.Unlock()
+
+	if err := writeCassette(file, interaction); err != nil {
+		_ = response.Body.Close()
+		return nil, err
+	}
+	response.Body = &recordingBody{
+		ReadCloser: response.Body,
+		onDone: func(data []byte) error {
+			interaction.Response.Body, interaction.Response.BodyEncoding = encodeBody(data)
+			return writeCassette(file, interaction)
+		},
+	}
+	return response, nil
+}
+
+// recordingBody buffers the body it reads and hands it to onDone once the body was read to the end,
+// or closed.
+type recordingBody struct {
+	io.ReadCloser
+	buf    bytes.Buffer
+	once   sync.Once
+	onDone func(data []byte) error
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		if saveErr := b.done(); saveErr != nil {
+			err = saveErr
+		}
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	if saveErr := b.done(); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+func (b *recordingBody) done() (err error) {
+	b.once.Do(func() {
+		err = b.onDone(b.buf.Bytes())
+	})
+	return err
+}
+
+// ReplayTransport is a http.RoundTripper that serves the responses of recorded cassette files.  Requests
+// are matched on their method, path, query and body.  Interactions are played in order, and the last
+// matching one is played again once all of them were used.  Unmatched requests fail.
+type ReplayTransport struct {
+	Interactions []*Interaction
+
+	mu   sync.Mutex
+	used map[*Interaction]bool
+}
+
+// NewReplayTransport loads the cassette files of a directory.
+func NewReplayTransport(dir string) (*ReplayTransport, error) {
+	files, err := cassetteFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	t := &ReplayTransport{}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		interaction := &Interaction{}
+		if err := yaml.Unmarshal(data, interaction); err != nil {
+			return nil, errors.Wrapf(err, "invalid cassette file: %s", file)
+		}
+		t.Interactions = append(t.Interactions, interaction)
+	}
+	return t, nil
+}
+
+func (t *ReplayTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request, body, err := readBody(request)
+	if err != nil {
+		return nil, err
+	}
+	actual := recordedRequest(request, body)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.used == nil {
+		t.used = map[*Interaction]bool{}
+	}
+	var match *Interaction
+	for _, interaction := range t.Interactions {
+		if interaction.Request.matches(actual) {
+			match = interaction
+			if !t.used[interaction] {
+				break
+			}
+		}
+	}
+	if match == nil {
+		return nil, errors.Errorf("no recorded interaction matches the request: %s %s", request.Method, request.URL.RequestURI())
+	}
+	t.used[match] = true
+
+	data, err := decodeBody(match.Response.Body, match.Response.BodyEncoding)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	for k, v := range match.Response.Header {
+		header[k] = v
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", match.Response.Status, http.StatusText(match.Response.Status)),
+		StatusCode:    match.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       request,
+	}, nil
+}
+
+func (r RecordedRequest) matches(actual RecordedRequest) bool {
+	if r.Method != actual.Method || r.Path != actual.Path || r.Query != actual.Query {
+		return false
+	}
+	if r.Body == actual.Body && r.BodyEncoding == actual.BodyEncoding {
+		return true
+	}
+	// JSON bodies match if they hold the same values.
+	var expected, got interface{}
+	if json.Unmarshal([]byte(r.Body), &expected) != nil || json.Unmarshal([]byte(actual.Body), &got) != nil {
+		return false
+	}
+	e, _ := json.Marshal(expected)
+	g, _ := json.Marshal(got)
+	return bytes.Equal(e, g)
+}
+
+func recordedRequest(request *http.Request, body []byte) RecordedRequest {
+	result := RecordedRequest{
+		Method: request.Method,
+		Path:   request.URL.EscapedPath(),
+		// the query is encoded with sorted keys, so that the parameters order does not matter.
+		Query: request.URL.Query().Encode(),
+	}
+	result.Body, result.BodyEncoding = encodeBody(normalizeBoundary(request, body))
+	return result
+}
+
+// normalizeBoundary replaces the boundary of a multipart body by the cassetteBoundary.
+func normalizeBoundary(request *http.Request, body []byte) []byte {
+	mediaType, params, err := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return body
+	}
+	return bytes.ReplaceAll(body, []byte(params["boundary"]), []byte(cassetteBoundary))
+}
+
+// readBody reads the whole request body in memory, it returns a copy of the request that can still be sent.
+func readBody(request *http.Request) (*http.Request, []byte, error) {
+	if request.Body == nil || request.Body == http.NoBody {
+		return request, nil, nil
+	}
+	data, err := ioutil.ReadAll(request.Body)
+	_ = request.Body.Close()
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	request = request.Clone(request.Context())
+	request.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return request, data, nil
+}
+
+func encodeBody(data []byte) (string, string) {
+	if utf8.Valid(data) {
+		return string(data), ""
+	}
+	return base64.StdEncoding.EncodeToString(data), "base64"
+}
+
+func decodeBody(body string, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return []byte(body), nil
+	case "base64":
+		data, err := base64.StdEncoding.DecodeString(body)
+		return data, errors.WithStack(err)
+	}
+	return nil, errors.Errorf("invalid cassette body encoding: %s", encoding)
+}
+
+func writeCassette(file string, interaction *Interaction) error {
+	data, err := yaml.Marshal(interaction)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(ioutil.WriteFile(file, data, 0644))
+}
+
+func cassetteFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
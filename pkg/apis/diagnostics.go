@@ -0,0 +1,120 @@
+package apis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+)
+
+// Severity ranks the diagnostics reported while mapping an openapi document.
+type Severity int
+
+const (
+	// SeverityInfo reports a mapping decision, nothing was dropped.
+	SeverityInfo Severity = iota
+	// SeverityWarning reports a part of an operation, like a field or parameter, that was not mapped.
+	SeverityWarning
+	// SeverityError reports an operation that was not mapped.
+	SeverityError
+)
+
+var severityNames = []string{"info", "warning", "error"}
+
+func (s Severity) String() string {
+	if int(s) < len(severityNames) {
+		return severityNames[s]
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	severity, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = severity
+	return nil
+}
+
+// ParseSeverity parses a severity name: "info", "warning" or "error".
+func ParseSeverity(name string) (Severity, error) {
+	for i, n := range severityNames {
+		if strings.EqualFold(n, name) {
+			return Severity(i), nil
+		}
+	}
+	return 0, errors.Errorf("invalid severity: %s", name)
+}
+
+// Diagnostic describes a part of the openapi document that was not mapped as expected.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	// Pointer is the JSON pointer to the openapi document element, like `/paths/~1pets/get`.
+	Pointer string `json:"pointer,omitempty"`
+	// Path is the GraphQL schema element, like `Query.listPets`.
+	Path   string `json:"path,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Diagnostics collects the diagnostics reported while generating the GraphQL schema.  Set it
+// in the Config.Diagnostics field to get them.
+type Diagnostics struct {
+	Entries []Diagnostic `json:"diagnostics"`
+}
+
+// Count returns the number of diagnostics with at least the given severity.
+func (d *Diagnostics) Count(min Severity) int {
+	count := 0
+	for _, entry := range d.Entries {
+		if entry.Severity >= min {
+			count++
+		}
+	}
+	return count
+}
+
+// report logs a mapping problem and collects it in the configured diagnostics.
+func (builder *builder) report(severity Severity, pointer string, path string, format string, v ...interface{}) {
+	reason := fmt.Sprintf(format, v...)
+	builder.options.Log.Println(reason)
+	if builder.options.Diagnostics != nil {
+		builder.options.Diagnostics.Entries = append(builder.options.Diagnostics.Entries, Diagnostic{
+			Severity: severity,
+			Pointer:  pointer,
+			Path:     path,
+			Reason:   reason,
+		})
+	}
+}
+
+// at sets the JSON pointer of the schema being mapped, the returned function restores the previous one.
+func (builder *builder) at(pointer string) func() {
+	previous := builder.pointer
+	builder.pointer = pointer
+	return func() {
+		builder.pointer = previous
+	}
+}
+
+// schemaPointer returns the JSON pointer of a schema: the one of its reference, or the given one
+// for inline schemas.
+func schemaPointer(sf *openapi3.SchemaRef, pointer string) string {
+	if sf != nil && sf.Ref != "" {
+		if i := strings.Index(sf.Ref, "#"); i >= 0 {
+			return sf.Ref[i+1:]
+		}
+	}
+	return pointer
+}
+
+func operationPointer(operation *openapi3.Operation) string {
+	path, _ := operation.Extensions["path"].(string)
+	method, _ := operation.Extensions["method"].(string)
+	return "/paths/" + escapePointerToken(path) + "/" + strings.ToLower(method)
+}
@@ -0,0 +1,137 @@
+package apis
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/chirino/graphql/inputconv"
+	"github.com/chirino/graphql/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// constEnumValue returns the GraphQL enum value name of a string `const` schema.  It returns false for
+// the other schemas, and for the string values that no enum value name can stand for.
+func constEnumValue(s *openapi3.Schema) (string, string, bool) {
+	if s == nil || len(s.Enum) != 1 || s.Extensions[constExtension] == nil {
+		return "", "", false
+	}
+	value, ok := s.Enum[0].(string)
+	if !ok {
+		return "", "", false
+	}
+	name := sanitizeName(value)
+	switch name {
+	case "", "true", "false", "null":
+		return "", "", false
+	}
+	return name, value, true
+}
+
+// ConstEnumType returns the single value enum type of a string `const` schema.  The enum value is
+// converted back to the const value when it had to be renamed to be a valid GraphQL name.
+func (builder *builder) ConstEnumType(typeName string, name string, value string) schema.Type {
+	draft := builder.draft
+	t := draft.Types[typeName]
+	if t != nil {
+		return t
+	}
+	t = &schema.Enum{
+		Name:   typeName,
+		Values: []*schema.EnumValue{{Name: name}},
+		Desc:   desc(fmt.Sprintf("The `%s` constant", value)),
+	}
+	draft.Types[typeName] = t
+	if name == value {
+		return t
+	}
+
+	builder.addInputConverter(typeName, func(t schema.Type, v interface{}) (interface{}, error) {
+		if v == name {
+			return value, nil
+		}
+		return v, nil
+	})
+	converter := func(v reflect.Value, err error) (reflect.Value, error) {
+		if err != nil {
+			return v, err
+		}
+		return reflect.ValueOf(renameConst(v.Interface(), value, name)), nil
+	}
+	for _, fieldType := range []string{"%s", "%s!", "[%s]", "[%s!]", "[%s]!", "[%s!]!"} {
+		builder.resultConverters[fmt.Sprintf(fieldType, typeName)] = converter
+	}
+	return t
+}
+
+// renameConst replaces the const value by its enum value name, in lists too.
+func renameConst(v interface{}, value string, name string) interface{} {
+	switch v := v.(type) {
+	case string:
+		if v == value {
+			return name
+		}
+	case []interface{}:
+		renamed := make([]interface{}, len(v))
+		for i, item := range v {
+			renamed[i] = renameConst(item, value, name)
+		}
+		return renamed
+	}
+	return v
+}
+
+// typeConverters converts the GraphQL input values back to the values of the openapi schemas.  It works
+// like inputconv.TypeConverters, which does not support enum types.
+type typeConverters inputconv.TypeConverters
+
+func (tc typeConverters) Convert(t schema.Type, value interface{}, path string) (interface{}, error) {
+	var result interface{}
+	switch t := t.(type) {
+	case *schema.Scalar, *schema.Enum:
+		result = value
+	case *schema.NonNull:
+		if value == nil {
+			panic(fmt.Sprintf("Expecting non null value, but got one. (field path: %s)", path))
+		}
+		cv, err := tc.Convert(t.OfType, value, path)
+		if err != nil {
+			return nil, err
+		}
+		result = cv
+	case *schema.List:
+		if value == nil {
+			break
+		}
+		list := value.([]interface{})
+		for i, cv := range list {
+			cv, err := tc.Convert(t.OfType, cv, path)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = cv
+		}
+		result = list
+	case *schema.InputObject:
+		if value == nil {
+			break
+		}
+		fields := value.(map[string]interface{})
+		converted := make(map[string]interface{}, len(fields))
+		for _, field := range t.Fields {
+			cv, err := tc.Convert(field.Type, fields[field.Name], path+"/"+field.Name)
+			if err != nil {
+				return nil, err
+			}
+			if cv != nil {
+				converted[field.Name] = cv
+			}
+		}
+		result = converted
+	default:
+		panic(fmt.Sprintf("convert not implemented for type %T: ", t))
+	}
+	if converter := tc[t.String()]; converter != nil {
+		return converter(t, result)
+	}
+	return result, nil
+}
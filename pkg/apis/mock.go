@@ -0,0 +1,302 @@
+package apis
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// MockOptions configures the mock mode, in which the API is not called.  Responses are built from the
+// examples of the openapi document, or generated from the response schemas.
+type MockOptions struct {
+	// Enabled turns on the mock mode.
+	Enabled bool `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	// Seed changes the generated data.  A request always gets the same data for a given seed.
+	Seed int64 `yaml:"seed,omitempty" json:"seed,omitempty"`
+}
+
+// mockMaxDepth limits the nesting of generated objects, so that recursive schemas terminate.
+const mockMaxDepth = 4
+
+// mockMaxInteger bounds the generated integers, it is the largest integer a float64 holds exactly.
+const mockMaxInteger = 1 << 53
+
+// mockEventCount is the number of events of mocked streamed responses.
+const mockEventCount = 3
+
+// mock returns the response of an operation without calling the API.  The input values that match
+// response properties are echoed back, so that mutations return what was sent.
+func (resolver resolver) mock(method string, url string, operation *openapi3.Operation, expectedStatus []int, stream *streamContent, args map[string]interface{}, body interface{}) (interface{}, error) {
+	bodyJSON, _ := json.Marshal(toJSONValue(body))
+	hash := fnv.New64a()
+	_, _ = fmt.Fprintf(hash, "%s %s %s", method, url, bodyJSON)
+	g := &mockGenerator{rand: rand.New(rand.NewSource(resolver.options.Mock.Seed ^ int64(hash.Sum64())))}
+
+	if stream != nil {
+		buf := bytes.Buffer{}
+		for i := 0; i < mockEventCount; i++ {
+			var event interface{}
+			if items := watchListItems(stream); items != nil {
+				event = map[string]interface{}{"type": "ADDED", "object": g.value(items, "object", 0)}
+			} else if stream.content != nil {
+				event = g.value(stream.content.Schema, "event", 0)
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return nil, err
+			}
+			if stream.format == sseStream {
+				buf.WriteString("data: ")
+				buf.Write(data)
+				buf.WriteString("\n\n")
+			} else {
+				buf.Write(data)
+				buf.WriteString("\n")
+			}
+		}
+		return newEventStream(ioutil.NopCloser(&buf), stream.format), nil
+	}
+
+	for _, status := range expectedStatus {
+		response := operation.Responses.Get(status)
+		if response == nil || response.Value == nil {
+			continue
+		}
+		if response.Value.Content == nil {
+			return "", nil
+		}
+		_, content, format := resolver.responseContent(response.Value.Content)
+		var result interface{}
+		if content != nil {
+			result = mockExample(content)
+			if result == nil {
+				result = g.value(content.Schema, "", 0)
+				if content.Schema != nil {
+					result = resolver.echo(result, content.Schema.Value, operation, args, body)
+				}
+			}
+		}
+		switch format {
+		case textResponse, binaryResponse:
+			text, ok := result.(string)
+			if !ok {
+				data, err := json.Marshal(result)
+				if err != nil {
+					return nil, err
+				}
+				text = string(data)
+			}
+			if format == binaryResponse {
+				text = base64.StdEncoding.EncodeToString([]byte(text))
+			}
+			return text, nil
+		}
+		return result, nil
+	}
+	return "", nil
+}
+
+// mockExample returns the example of a response content, or nil if it has none.
+func mockExample(content *openapi3.MediaType) interface{} {
+	if content.Example != nil {
+		return content.Example
+	}
+	var names []string
+	for name, example := range content.Examples {
+		if example != nil && example.Value != nil && example.Value.Value != nil {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		sort.Strings(names)
+		return content.Examples[names[0]].Value.Value
+	}
+	return nil
+}
+
+// echo copies the parameters and the request body properties that are also properties of the result.
+func (resolver resolver) echo(result interface{}, s *openapi3.Schema, operation *openapi3.Operation, args map[string]interface{}, body interface{}) interface{} {
+	object, ok := result.(map[string]interface{})
+	if !ok || s == nil {
+		return result
+	}
+	declared := map[string]bool{}
+	propertyNames(s, declared, map[*openapi3.Schema]bool{})
+	for _, param := range operation.Parameters {
+		name := param.Value.Name
+		if v := args[resolver.options.fieldName(name)]; declared[name] && v != nil {
+			object[name] = toJSONValue(v)
+		}
+	}
+	if input, ok := toJSONValue(body).(map[string]interface{}); ok {
+		for k, v := range input {
+			if declared[k] && v != nil {
+				object[k] = v
+			}
+		}
+	}
+	return object
+}
+
+// mockGenerator synthesizes JSON values that match a schema.
+type mockGenerator struct {
+	rand *rand.Rand
+}
+
+func (g *mockGenerator) value(ref *openapi3.SchemaRef, name string, depth int) interface{} {
+	if ref == nil || ref.Value == nil {
+		return nil
+	}
+	s := ref.Value
+	if s.Example != nil {
+		return s.Example
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[g.rand.Intn(len(s.Enum))]
+	}
+	if s.Default != nil {
+		return s.Default
+	}
+	if len(s.AllOf) > 0 {
+		result := map[string]interface{}{}
+		for _, ref := range s.AllOf {
+			if object, ok := g.value(ref, name, depth).(map[string]interface{}); ok {
+				for k, v := range object {
+					result[k] = v
+				}
+			}
+		}
+		g.properties(s, result, depth)
+		return result
+	}
+	for _, alternatives := range []openapi3.SchemaRefs{s.OneOf, s.AnyOf} {
+		if len(alternatives) > 0 {
+			return g.value(alternatives[0], name, depth)
+		}
+	}
+
+	switch s.Type {
+	case "array":
+		if depth >= mockMaxDepth {
+			return []interface{}{}
+		}
+		count := 2
+		if s.MaxItems != nil && uint64(count) > *s.MaxItems {
+			count = int(*s.MaxItems)
+		}
+		if uint64(count) < s.MinItems {
+			count = int(s.MinItems)
+		}
+		result := []interface{}{}
+		for i := 0; i < count; i++ {
+			result = append(result, g.value(s.Items, name, depth+1))
+		}
+		return result
+	case "integer":
+		min, max := 1.0, 100.0
+		if s.Min != nil {
+			min = *s.Min
+		}
+		if s.Max != nil {
+			max = *s.Max
+		}
+		// keep the range within the integers a float64 holds exactly, so that it can't overflow an int64.
+		min = math.Max(math.Min(min, mockMaxInteger), -mockMaxInteger)
+		max = math.Max(math.Min(max, mockMaxInteger), -mockMaxInteger)
+		if max < min {
+			max = min
+		}
+		return float64(int64(min) + g.rand.Int63n(int64(max-min)+1))
+	case "number":
+		min, max := 0.0, 100.0
+		if s.Min != nil {
+			min = *s.Min
+		}
+		if s.Max != nil {
+			max = *s.Max
+		}
+		return float64(int64((min+g.rand.Float64()*(max-min))*100)) / 100
+	case "boolean":
+		return g.rand.Intn(2) == 1
+	case "string":
+		return g.string(s, name)
+	case "object", "":
+		if s.Type == "" && len(s.Properties) == 0 {
+			return nil
+		}
+		result := map[string]interface{}{}
+		if depth < mockMaxDepth {
+			g.properties(s, result, depth)
+		}
+		return result
+	}
+	return nil
+}
+
+func (g *mockGenerator) properties(s *openapi3.Schema, result map[string]interface{}, depth int) {
+	for _, name := range sortedSchemaNames(s.Properties) {
+		ref := s.Properties[name]
+		// writeOnly properties are never sent by the API.
+		if ref.Value != nil && ref.Value.WriteOnly {
+			continue
+		}
+		result[name] = g.value(ref, name, depth+1)
+	}
+}
+
+var mockEpoch = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func (g *mockGenerator) string(s *openapi3.Schema, name string) string {
+	if name == "" {
+		name = "value"
+	}
+	n := g.rand.Intn(1000)
+	var text string
+	switch s.Format {
+	case "date-time":
+		text = mockEpoch.Add(time.Duration(g.rand.Intn(365*24*60)) * time.Minute).Format(time.RFC3339)
+	case "date":
+		text = mockEpoch.AddDate(0, 0, g.rand.Intn(365)).Format("2006-01-02")
+	case "time":
+		text = mockEpoch.Add(time.Duration(g.rand.Intn(24*60*60)) * time.Second).Format("15:04:05")
+	case "email":
+		text = fmt.Sprintf("user%d@example.com", n)
+	case "uuid":
+		data := make([]byte, 16)
+		g.rand.Read(data)
+		data[6] = data[6]&0x0f | 0x40
+		data[8] = data[8]&0x3f | 0x80
+		text = fmt.Sprintf("%x-%x-%x-%x-%x", data[0:4], data[4:6], data[6:8], data[8:10], data[10:])
+	case "uri", "url":
+		text = fmt.Sprintf("https://example.com/%s/%d", strings.ToLower(name), n)
+	case "hostname":
+		text = fmt.Sprintf("host%d.example.com", n)
+	case "ipv4":
+		text = fmt.Sprintf("192.0.2.%d", g.rand.Intn(255))
+	case "ipv6":
+		text = fmt.Sprintf("2001:db8::%x", n)
+	case "byte":
+		text = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s-%d", name, n)))
+	case "password":
+		text = "********"
+	default:
+		text = fmt.Sprintf("%s-%d", name, n)
+	}
+	for uint64(len(text)) < s.MinLength {
+		text += "x"
+	}
+	if s.MaxLength != nil && uint64(len(text)) > *s.MaxLength {
+		text = text[:*s.MaxLength]
+	}
+	return text
+}
@@ -0,0 +1,118 @@
+package apis
+
+import (
+	"reflect"
+
+	"github.com/chirino/graphql/resolvers"
+	"github.com/chirino/graphql/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// NamespaceOptions groups the query fields into namespace objects, like `query { pets { listPets } }`.
+// Mutation fields are only grouped when Mutations is set, and subscription fields are never grouped.
+type NamespaceOptions struct {
+	// Enabled groups the operations by their first tag.  Operations without tags stay on the root types.
+	Enabled bool `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	// Mutations also groups the mutation fields.  GraphQL only executes the root mutation fields serially,
+	// so the mutations selected in the same namespace object then run in parallel.
+	Mutations bool `yaml:"mutations,omitempty" json:"mutations,omitempty"`
+	// Operations maps operation ids to the namespace to group them in, overriding their tags.
+	Operations map[string]string `yaml:"operations,omitempty" json:"operations,omitempty"`
+}
+
+// namespace returns the name of the namespace of an operation, or an empty string if it is not grouped.
+func (builder *builder) namespace(operation *openapi3.Operation) string {
+	if operation.OperationID != "" {
+		if namespace, ok := builder.options.Namespaces.Operations[operation.OperationID]; ok {
+			return namespace
+		}
+	}
+	if builder.options.Namespaces.Enabled && len(operation.Tags) > 0 {
+		return operation.Tags[0]
+	}
+	return ""
+}
+
+// namespaceType returns the name of the type holding the fields of the operation, creating its namespace
+// object and the root field that leads to it if needed.  It returns the root type when the operation is
+// not grouped.
+func (builder *builder) namespaceType(rootType string, operation *openapi3.Operation) string {
+	if rootType == builder.options.MutationType && !builder.options.Namespaces.Mutations {
+		// keep the mutations on the root type so that they are executed serially.
+		return rootType
+	}
+	namespace := builder.namespace(operation)
+	if namespace == "" {
+		return rootType
+	}
+	draft := builder.draft
+	fieldName := builder.options.fieldName(namespace)
+	typeName := rootType + capitalizeFirstLetter(fieldName)
+
+	var rootObject *schema.Object
+	if t, ok := draft.Types[rootType]; ok {
+		rootObject = t.(*schema.Object)
+	} else {
+		rootObject = &schema.Object{
+			Name: rootType,
+		}
+		draft.Types[rootType] = rootObject
+	}
+
+	if field := rootObject.Fields.Get(fieldName); field != nil {
+		if schema.DeepestType(field.Type).String() == typeName {
+			return typeName
+		}
+		builder.report(SeverityInfo, operationPointer(operation), rootType+"."+fieldName, "not grouping operations in the %s.%s namespace: field already exists", rootType, fieldName)
+		return rootType
+	}
+	if _, exists := draft.Types[typeName]; exists {
+		builder.report(SeverityInfo, operationPointer(operation), rootType+"."+fieldName, "not grouping operations in the %s.%s namespace: type %s already exists", rootType, fieldName, typeName)
+		return rootType
+	}
+
+	text := ""
+	if tag := builder.tags[namespace]; tag != nil {
+		text = tag.Description
+	}
+	object := &schema.Object{
+		Name: typeName,
+		Desc: desc(text),
+	}
+	draft.Types[typeName] = object
+	rootObject.Fields = append(rootObject.Fields, &schema.Field{
+		Name: fieldName,
+		Desc: desc(text),
+		Type: &schema.NonNull{OfType: object},
+	})
+	builder.resolvers[rootType+":"+fieldName] = resolvers.Func(func(request *resolvers.ResolveRequest, _ resolvers.Resolution) resolvers.Resolution {
+		return func() (reflect.Value, error) {
+			// the namespace fields don't need a parent value.
+			return reflect.ValueOf(map[string]interface{}{}), nil
+		}
+	})
+	return typeName
+}
+
+// removeEmptyNamespaces removes the namespaces whose operations could not be mapped.
+func (builder *builder) removeEmptyNamespaces(rootType string) {
+	rootObject, ok := builder.draft.Types[rootType].(*schema.Object)
+	if !ok {
+		return
+	}
+	fields := schema.FieldList{}
+	for _, field := range rootObject.Fields {
+		if nonNull, ok := field.Type.(*schema.NonNull); ok {
+			if object, ok := nonNull.OfType.(*schema.Object); ok && len(object.Fields) == 0 {
+				delete(builder.draft.Types, object.Name)
+				delete(builder.resolvers, rootType+":"+field.Name)
+				continue
+			}
+		}
+		fields = append(fields, field)
+	}
+	rootObject.Fields = fields
+	if len(fields) == 0 {
+		delete(builder.draft.Types, rootType)
+	}
+}
@@ -0,0 +1,180 @@
+package apis
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/chirino/graphql/resolvers"
+	"github.com/chirino/graphql/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	// NamingAsIs keeps the openapi names, only replacing the characters that are not valid in GraphQL names.
+	NamingAsIs = "as-is"
+	// NamingCamelCase converts names like `animal_type_id` or `X-Request-Id` to `animalTypeId` and `xRequestId`.
+	NamingCamelCase = "camelCase"
+	// NamingPascalCase converts names like `animal_type_id` or `X-Request-Id` to `AnimalTypeId` and `XRequestId`.
+	NamingPascalCase = "PascalCase"
+)
+
+// fieldName converts the name of an openapi property, parameter or operation to a GraphQL field
+// or argument name following the configured naming convention.
+func (o Config) fieldName(name string) string {
+	switch o.FieldNaming {
+	case NamingCamelCase, NamingPascalCase:
+		words := strings.FieldsFunc(name, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(words) == 0 {
+			break
+		}
+		for i, word := range words {
+			if i == 0 && o.FieldNaming == NamingCamelCase {
+				if strings.ToUpper(word) == word {
+					words[i] = strings.ToLower(word)
+				} else {
+					words[i] = strings.ToLower(word[:1]) + word[1:]
+				}
+			} else {
+				words[i] = capitalizeFirstLetter(word)
+			}
+		}
+		return sanitizeName(strings.Join(words, ""))
+	}
+	return sanitizeName(name)
+}
+
+// wireNameResolver resolves a field that was renamed by the naming convention from the
+// json object entry holding it.
+func wireNameResolver(wireName string) resolvers.Func {
+	key := reflect.ValueOf(wireName)
+	return func(request *resolvers.ResolveRequest, next resolvers.Resolution) resolvers.Resolution {
+		parent := resolvers.Dereference(request.Parent)
+		if parent.Kind() != reflect.Map || parent.Type().Key().Kind() != reflect.String {
+			return next
+		}
+		return func() (reflect.Value, error) {
+			return parent.MapIndex(key), nil
+		}
+	}
+}
+
+// wireNamesConverter renames the fields of an input object back to the names the API uses.
+func wireNamesConverter(wireNames map[string]string) func(t schema.Type, value interface{}) (interface{}, error) {
+	return func(t schema.Type, value interface{}) (interface{}, error) {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value, nil
+		}
+		result := make(map[string]interface{}, len(object))
+		for k, v := range object {
+			if wireName, ok := wireNames[k]; ok {
+				k = wireName
+			}
+			result[k] = v
+		}
+		return result, nil
+	}
+}
+
+// addInputConverter registers a converter for the named input type, running it after the
+// converters already registered for the type.
+func (builder *builder) addInputConverter(typeName string, converter func(t schema.Type, value interface{}) (interface{}, error)) {
+	previous := builder.inputConverters[typeName]
+	if previous == nil {
+		builder.inputConverters[typeName] = converter
+		return
+	}
+	builder.inputConverters[typeName] = func(t schema.Type, value interface{}) (interface{}, error) {
+		value, err := previous(t, value)
+		if err != nil {
+			return nil, err
+		}
+		return converter(t, value)
+	}
+}
+
+// TypeNameFunc names the GraphQL type generated for an openapi schema.  ref is the reference of
+// the schema, or empty for inline schemas.  defaultName is the name used when the function returns
+// an empty string: the component name of referenced schemas, or a name derived from the position
+// of inline schemas, like `QueryGetPetArgFilter` for the filter parameter of the getPet operation.
+// The Input or Result suffix is appended to the returned name.
+type TypeNameFunc func(ref string, defaultName string, s *openapi3.Schema) string
+
+// typeName returns the name of the GraphQL type generated for a schema, without its Input or Result suffix.
+func (builder *builder) typeName(sf *openapi3.SchemaRef, pathBasedTypeName string) string {
+	name := pathBasedTypeName
+	if sf.Ref != "" {
+		name = strings.TrimPrefix(sf.Ref, "#/components/schemas/")
+	}
+	if builder.options.TypeName != nil {
+		if custom := builder.options.TypeName(sf.Ref, name, sf.Value); custom != "" {
+			name = custom
+		}
+	}
+	return sanitizeName(name)
+}
+
+// typeCacheKey returns the key used to reuse the type generated for a schema.  Inline object schemas
+// are keyed by their structure so that identical schemas used in several places share one type.
+func typeCacheKey(sf *openapi3.SchemaRef, inputType bool) string {
+	prefix := "o:"
+	if inputType {
+		prefix = "i:"
+	}
+	if sf.Ref != "" {
+		return prefix + sf.Ref
+	}
+	if !hasProperties(sf.Value, map[*openapi3.Schema]bool{}) {
+		return ""
+	}
+	data, err := json.Marshal(sf.Value)
+	if err != nil {
+		return ""
+	}
+	return prefix + "inline:" + string(data)
+}
+
+// inlineName is a position of an inline schema, the type shared by identical inline schemas is named
+// after their preferred position.
+type inlineName struct {
+	path string
+	// component is set for the schemas declared in a component of the openapi document, when the path is
+	// the one of that component.
+	component bool
+}
+
+// preferred tells whether the name is preferred over another one: the positions in components come first,
+// so that their inline schemas are named after their component, then the smallest path.
+func (n inlineName) preferred(other inlineName) bool {
+	if n.component != other.component {
+		return n.component
+	}
+	return n.path < other.path
+}
+
+// addInlineName records a position of an inline schema.
+func (builder *builder) addInlineName(cacheKey string, path string) {
+	name := inlineName{path: path}
+	if strings.HasPrefix(builder.pointer, "/components/schemas/") {
+		component := strings.SplitN(strings.TrimPrefix(builder.pointer, "/components/schemas/"), "/", 2)[0]
+		name.component = strings.HasPrefix(path, sanitizeName(unescapePointerToken(component)))
+	}
+	if existing, ok := builder.inlineNames[cacheKey]; !ok || name.preferred(existing) {
+		builder.inlineNames[cacheKey] = name
+	}
+}
+
+// propValueTypeName returns the name of a property wrapper value type, list values get a List suffix.
+func propValueTypeName(t schema.Type) string {
+	switch t := t.(type) {
+	case *schema.NonNull:
+		return propValueTypeName(t.OfType)
+	case *schema.List:
+		return propValueTypeName(t.OfType) + "List"
+	}
+	return t.String()
+}
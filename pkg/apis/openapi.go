@@ -2,6 +2,7 @@ package apis
 
 import (
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"github.com/getkin/kin-openapi/openapi2"
 	"github.com/getkin/kin-openapi/openapi2conv"
@@ -12,6 +13,7 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"strings"
 )
 
 func LoadOpenApiV2orV3Doc(docLocation EndpointOptions) (*openapi3.T, error) {
@@ -54,14 +56,26 @@ func LoadOpenApiV2orV3Doc(docLocation EndpointOptions) (*openapi3.T, error) {
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
+		applyCollectionFormats(&swagger2, apiDoc)
+		applyStreamingProduces(&swagger2, apiDoc)
 		enrichApiDoc(apiDoc)
 		return apiDoc, nil
 	} else {
+		if strings.HasPrefix(doc.OpenAPI, "3.1") {
+			data, err = downgradeOpenAPI31(data)
+			if err != nil {
+				return nil, errors.Wrap(err, "could not convert the openapi 3.1 document")
+			}
+		}
 		// It should be a v3 document already..
 		apiDoc, err := openapi3.NewLoader().LoadFromDataWithPath(data, location)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
+		err = loadWebhooks(apiDoc, location)
+		if err != nil {
+			return nil, err
+		}
 		enrichApiDoc(apiDoc)
 		return apiDoc, nil
 	}
@@ -107,6 +121,139 @@ func enrichApiDoc(doc *openapi3.T) {
 	}
 }
 
+// applyCollectionFormats maps the swagger 2 collectionFormat of array parameters to the equivalent
+// openapi 3 style and explode settings since the openapi2conv package does not.
+func applyCollectionFormats(swagger2 *openapi2.T, doc *openapi3.T) {
+	apply := func(v2 *openapi2.Parameter, v3 *openapi3.Parameter) {
+		if v2 == nil || v3 == nil || v2.Type != "array" {
+			return
+		}
+		format := v2.CollectionFormat
+		if format == "" {
+			format = "csv"
+		}
+		explode := false
+		switch format {
+		case "csv":
+			if v3.In == openapi3.ParameterInQuery || v3.In == openapi3.ParameterInCookie {
+				v3.Style = openapi3.SerializationForm
+			} else {
+				v3.Style = openapi3.SerializationSimple
+			}
+		case "ssv":
+			v3.Style = openapi3.SerializationSpaceDelimited
+		case "pipes":
+			v3.Style = openapi3.SerializationPipeDelimited
+		case "multi":
+			v3.Style = openapi3.SerializationForm
+			explode = true
+		case "tsv":
+			if v3.Extensions == nil {
+				v3.Extensions = map[string]interface{}{}
+			}
+			v3.Extensions[collectionFormatExtension] = format
+		}
+		v3.Explode = &explode
+	}
+
+	find := func(v2 *openapi2.Parameter) *openapi2.Parameter {
+		if v2 != nil && v2.Ref != "" {
+			return swagger2.Parameters[strings.TrimPrefix(v2.Ref, "#/parameters/")]
+		}
+		return v2
+	}
+
+	applyAll := func(v2Params openapi2.Parameters, v3Params openapi3.Parameters) {
+		for _, v2 := range v2Params {
+			v2 = find(v2)
+			if v2 == nil {
+				continue
+			}
+			if p := v3Params.GetByInAndName(v2.In, v2.Name); p != nil {
+				apply(v2, p)
+			}
+		}
+	}
+
+	for path, v2Item := range swagger2.Paths {
+		v3Item := doc.Paths[path]
+		if v3Item == nil {
+			continue
+		}
+		applyAll(v2Item.Parameters, v3Item.Parameters)
+		for method, v2Op := range v2Item.Operations() {
+			if v3Op := v3Item.GetOperation(method); v3Op != nil {
+				applyAll(v2Op.Parameters, v3Op.Parameters)
+			}
+		}
+	}
+}
+
+// applyStreamingProduces adds the streaming media types listed in the produces of swagger 2 operations
+// to the content of their responses since the openapi2conv package only keeps the json content.
+func applyStreamingProduces(swagger2 *openapi2.T, doc *openapi3.T) {
+	for path, v2Item := range swagger2.Paths {
+		v3Item := doc.Paths[path]
+		if v3Item == nil {
+			continue
+		}
+		for method, v2Op := range v2Item.Operations() {
+			v3Op := v3Item.GetOperation(method)
+			if v3Op == nil {
+				continue
+			}
+			for _, mediaType := range v2Op.Produces {
+				if !isStreamMediaType(mediaType) {
+					continue
+				}
+				for _, response := range v3Op.Responses {
+					if response.Value == nil || response.Value.Content == nil {
+						continue
+					}
+					if json := response.Value.Content.Get("application/json"); json != nil {
+						response.Value.Content[mediaType] = &openapi3.MediaType{Schema: json.Schema}
+					}
+				}
+			}
+		}
+	}
+}
+
+// loadWebhooks parses the webhooks of openapi 3.1 documents, which the openapi3 package keeps
+// as a raw extension, and resolves their references.
+func loadWebhooks(doc *openapi3.T, location *url.URL) error {
+	raw, ok := doc.Extensions[webhooksExtension].(json.RawMessage)
+	if !ok {
+		return nil
+	}
+	webhooks := openapi3.Paths{}
+	if err := json.Unmarshal(raw, &webhooks); err != nil {
+		return errors.Wrap(err, "invalid webhooks")
+	}
+
+	// Resolve the references using a document that shares the components.
+	resolved := &openapi3.T{
+		OpenAPI:    doc.OpenAPI,
+		Info:       doc.Info,
+		Components: doc.Components,
+		Paths:      openapi3.Paths{},
+	}
+	for name, item := range webhooks {
+		resolved.Paths["/"+name] = item
+	}
+	if err := openapi3.NewLoader().ResolveRefsIn(resolved, location); err != nil {
+		return errors.WithStack(err)
+	}
+	doc.Extensions[webhooksExtension] = webhooks
+	return nil
+}
+
+// ReadOpenapiDocument returns the content of the openapi document of an endpoint: its OpenapiDocument,
+// or the content of its URL.
+func ReadOpenapiDocument(endpointOptions EndpointOptions) ([]byte, error) {
+	return readURL(endpointOptions)
+}
+
 func readURL(endpointOptions EndpointOptions) ([]byte, error) {
 	if len(endpointOptions.OpenapiDocument) != 0 {
 		return endpointOptions.OpenapiDocument, nil
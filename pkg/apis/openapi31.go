@@ -0,0 +1,291 @@
+package apis
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// downgradeOpenAPI31 rewrites the JSON Schema 2020-12 keywords used by openapi 3.1 documents into
+// their openapi 3.0 equivalents so that the document can be loaded by the openapi3 package:
+//
+// * `type: [T, "null"]` becomes `type: T` and `nullable: true`
+// * `anyOf: [X, {type: "null"}]` becomes X
+// * `const: V` becomes `enum: [V]` marked with x-const, typed after V when the schema has no type
+// * `prefixItems` become `items`
+// * `$defs` are moved to the `components/schemas` section
+// * numeric `exclusiveMinimum` and `exclusiveMaximum` become `minimum` / `maximum` with boolean exclusive flags
+// * schema `examples` become an `example`
+// * `contentEncoding: base64` becomes `format: byte`
+func downgradeOpenAPI31(data []byte) ([]byte, error) {
+	data, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	refs := hoistDefs(doc)
+	downgradeNode(doc, refs)
+	doc["openapi"] = "3.0.3"
+	return json.Marshal(doc)
+}
+
+// hoistDefs moves all the `$defs` found in the document to the components/schemas section and returns
+// the mapping of their old references to the new ones.
+func hoistDefs(doc map[string]interface{}) map[string]string {
+	components, _ := doc["components"].(map[string]interface{})
+	if components == nil {
+		components = map[string]interface{}{}
+	}
+	schemas, _ := components["schemas"].(map[string]interface{})
+	if schemas == nil {
+		schemas = map[string]interface{}{}
+	}
+
+	refs := map[string]string{}
+	var visit func(node map[string]interface{}, pointer string)
+	visit = func(node map[string]interface{}, pointer string) {
+		defs, ok := node["$defs"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		delete(node, "$defs")
+
+		// name the hoisted schemas after the component schema that defined them.
+		owner := ""
+		if strings.HasPrefix(pointer, "/components/schemas/") {
+			owner = strings.Split(pointer, "/")[3]
+		}
+		for _, name := range sortedMapKeys(defs) {
+			hoisted := owner + capitalizeFirstLetter(name)
+			for i := 2; schemas[hoisted] != nil; i++ {
+				hoisted = fmt.Sprintf("%s%s%d", owner, capitalizeFirstLetter(name), i)
+			}
+			schemas[hoisted] = defs[name]
+			target := "#/components/schemas/" + escapePointerToken(hoisted)
+			refs["#"+pointer+"/$defs/"+escapePointerToken(name)] = target
+			// plain schema documents refer to their definitions from the root.
+			if _, ok := refs["#/$defs/"+escapePointerToken(name)]; !ok {
+				refs["#/$defs/"+escapePointerToken(name)] = target
+			}
+			walkObjects(defs[name], "/components/schemas/"+escapePointerToken(hoisted), false, visit)
+		}
+	}
+	walkObjects(doc, "", false, visit)
+
+	if len(schemas) > 0 {
+		components["schemas"] = schemas
+		doc["components"] = components
+	}
+	return refs
+}
+
+func downgradeNode(node interface{}, refs map[string]string) {
+	walkObjects(node, "", false, func(node map[string]interface{}, _ string) {
+		downgradeSchema(node, refs)
+	})
+}
+
+// walkObjects calls visit for all the objects in the document that are not instance data
+// like examples or default values.  names is true when the keys of node are names chosen by
+// the document author, like the names of the properties of a schema.
+func walkObjects(node interface{}, pointer string, names bool, visit func(node map[string]interface{}, pointer string)) {
+	switch node := node.(type) {
+	case map[string]interface{}:
+		if !names {
+			visit(node, pointer)
+		}
+		for _, key := range sortedMapKeys(node) {
+			if !names && isDataKeyword(key) {
+				continue
+			}
+			walkObjects(node[key], pointer+"/"+escapePointerToken(key), !names && isNamesKeyword(key), visit)
+		}
+	case []interface{}:
+		for i, item := range node {
+			walkObjects(item, fmt.Sprintf("%s/%d", pointer, i), false, visit)
+		}
+	}
+}
+
+func downgradeSchema(node map[string]interface{}, refs map[string]string) {
+	if ref, ok := node["$ref"].(string); ok {
+		if target, ok := refs[ref]; ok {
+			node["$ref"] = target
+		}
+	}
+
+	if types, ok := node["type"].([]interface{}); ok {
+		var nonNull []interface{}
+		for _, t := range types {
+			if t == "null" {
+				node["nullable"] = true
+			} else {
+				nonNull = append(nonNull, t)
+			}
+		}
+		if len(nonNull) == 1 {
+			node["type"] = nonNull[0]
+		} else {
+			// openapi 3.0 schemas can only have one type.
+			delete(node, "type")
+		}
+	} else if node["type"] == "null" {
+		delete(node, "type")
+		node["nullable"] = true
+	}
+
+	// `anyOf: [X, {type: "null"}]` is the usual way to make a reference nullable.
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		alternatives, ok := node[keyword].([]interface{})
+		if !ok {
+			continue
+		}
+		var nonNull []interface{}
+		for _, alternative := range alternatives {
+			if m, ok := alternative.(map[string]interface{}); ok && len(m) == 1 && m["type"] == "null" {
+				node["nullable"] = true
+			} else {
+				nonNull = append(nonNull, alternative)
+			}
+		}
+		if len(nonNull) == 1 {
+			delete(node, keyword)
+			m, _ := nonNull[0].(map[string]interface{})
+			if ref, ok := m["$ref"].(string); ok && len(m) == 1 && node["type"] == nil && node["properties"] == nil {
+				if target, ok := refs[ref]; ok {
+					ref = target
+				}
+				node["$ref"] = ref
+			} else {
+				node["allOf"] = nonNull
+			}
+		} else {
+			node[keyword] = nonNull
+		}
+	}
+
+	if value, ok := node["const"]; ok {
+		delete(node, "const")
+		node["enum"] = []interface{}{value}
+		node[constExtension] = true
+		if _, ok := node["type"]; !ok {
+			if t := jsonValueType(value); t != "" {
+				node["type"] = t
+			}
+		}
+	}
+
+	if prefixItems, ok := node["prefixItems"].([]interface{}); ok {
+		delete(node, "prefixItems")
+		if _, ok := node["items"]; !ok || node["items"] == false {
+			node["items"] = tupleItems(prefixItems)
+		}
+	}
+	if items, ok := node["items"].(bool); ok {
+		if items {
+			node["items"] = map[string]interface{}{}
+		} else {
+			delete(node, "items")
+		}
+	}
+
+	for _, bound := range []string{"Minimum", "Maximum"} {
+		if value, ok := node["exclusive"+bound].(float64); ok {
+			node[strings.ToLower(bound)] = value
+			node["exclusive"+bound] = true
+		}
+	}
+
+	if examples, ok := node["examples"].([]interface{}); ok {
+		delete(node, "examples")
+		if _, ok := node["example"]; !ok && len(examples) > 0 {
+			node["example"] = examples[0]
+		}
+	}
+
+	if node["contentEncoding"] == "base64" {
+		delete(node, "contentEncoding")
+		if _, ok := node["format"]; !ok {
+			node["format"] = "byte"
+		}
+	}
+}
+
+// tupleItems returns the items schema to use for a tuple: the item schema if all the
+// tuple items are the same, or a schema that accepts anything otherwise.
+func tupleItems(prefixItems []interface{}) interface{} {
+	if len(prefixItems) == 0 {
+		return map[string]interface{}{}
+	}
+	for _, item := range prefixItems[1:] {
+		if !reflect.DeepEqual(item, prefixItems[0]) {
+			return map[string]interface{}{}
+		}
+	}
+	return prefixItems[0]
+}
+
+// jsonValueType returns the schema type of a json value.
+func jsonValueType(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == float64(int64(value)) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return ""
+}
+
+// isDataKeyword reports if the keyword holds instance data instead of schemas or openapi objects.
+func isDataKeyword(key string) bool {
+	switch key {
+	case "example", "examples", "default", "enum", "const":
+		return true
+	}
+	return strings.HasPrefix(key, "x-")
+}
+
+// isNamesKeyword reports if the keys of the object held by the keyword are names that could
+// clash with keywords.
+func isNamesKeyword(key string) bool {
+	switch key {
+	case "properties", "patternProperties", "dependentSchemas", "$defs", "definitions", "schemas", "responses":
+		return true
+	}
+	return false
+}
+
+func escapePointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func unescapePointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,201 @@
+package apis
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// collectionFormatExtension holds the swagger 2 collectionFormat of parameters that have no
+// openapi 3 style equivalent (like tsv).
+const collectionFormatExtension = "x-collection-format"
+
+// paramValues holds the request parts that parameters get serialized into.
+type paramValues struct {
+	path    string
+	query   url.Values
+	headers http.Header
+	cookies []*http.Cookie
+}
+
+// serializeParam serializes the parameter value following the style and explode rules of
+// the openapi 3 spec: https://swagger.io/docs/specification/serialization/
+func (p *paramValues) serializeParam(param *openapi3.Parameter, value interface{}) error {
+	method, err := param.SerializationMethod()
+	if err != nil {
+		return err
+	}
+	style, explode := method.Style, method.Explode
+	if format, ok := param.Extensions[collectionFormatExtension].(string); ok && format == "tsv" {
+		style = "tabDelimited"
+	}
+
+	switch param.In {
+	case openapi3.ParameterInPath:
+		serialized := serializePathParam(param.Name, style, explode, value)
+		p.path = strings.ReplaceAll(p.path, "{"+param.Name+"}", serialized)
+
+	case openapi3.ParameterInQuery:
+		serializeQueryParam(p.query, param.Name, style, explode, value)
+
+	case openapi3.ParameterInHeader:
+		p.headers.Set(param.Name, serializeSimple(value, explode, false))
+
+	case openapi3.ParameterInCookie:
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if explode {
+				for _, k := range sortedKeys(value) {
+					p.cookies = append(p.cookies, &http.Cookie{Name: k, Value: primitiveString(value[k])})
+				}
+				return nil
+			}
+		}
+		p.cookies = append(p.cookies, &http.Cookie{
+			Name:  param.Name,
+			Value: serializeSimple(value, false, false),
+		})
+	}
+	return nil
+}
+
+func serializePathParam(name string, style string, explode bool, value interface{}) string {
+	switch style {
+	case openapi3.SerializationLabel:
+		separator := ","
+		if explode {
+			separator = "."
+		}
+		return "." + strings.Join(pairs(value, explode, true), separator)
+
+	case openapi3.SerializationMatrix:
+		switch value := value.(type) {
+		case []interface{}:
+			if explode {
+				result := ""
+				for _, item := range value {
+					result += ";" + name + "=" + url.PathEscape(primitiveString(item))
+				}
+				return result
+			}
+			return ";" + name + "=" + strings.Join(pairs(value, false, true), ",")
+		case map[string]interface{}:
+			if explode {
+				return ";" + strings.Join(pairs(value, true, true), ";")
+			}
+			return ";" + name + "=" + strings.Join(pairs(value, false, true), ",")
+		default:
+			return ";" + name + "=" + url.PathEscape(primitiveString(value))
+		}
+
+	default: // simple
+		return serializeSimple(value, explode, true)
+	}
+}
+
+func serializeSimple(value interface{}, explode bool, escape bool) string {
+	return strings.Join(pairs(value, explode, escape), ",")
+}
+
+func serializeQueryParam(query url.Values, name string, style string, explode bool, value interface{}) {
+	switch value := value.(type) {
+	case []interface{}:
+		items := make([]string, len(value))
+		for i, item := range value {
+			items[i] = primitiveString(item)
+		}
+		switch style {
+		case openapi3.SerializationSpaceDelimited:
+			query.Set(name, strings.Join(items, " "))
+		case openapi3.SerializationPipeDelimited:
+			query.Set(name, strings.Join(items, "|"))
+		case "tabDelimited":
+			query.Set(name, strings.Join(items, "\t"))
+		default:
+			if explode {
+				for _, item := range items {
+					query.Add(name, item)
+				}
+			} else {
+				query.Set(name, strings.Join(items, ","))
+			}
+		}
+
+	case map[string]interface{}:
+		switch {
+		case style == openapi3.SerializationDeepObject:
+			for _, k := range sortedKeys(value) {
+				query.Set(name+"["+k+"]", primitiveString(value[k]))
+			}
+		case explode:
+			for _, k := range sortedKeys(value) {
+				query.Set(k, primitiveString(value[k]))
+			}
+		default:
+			query.Set(name, strings.Join(pairs(value, false, false), ","))
+		}
+
+	default:
+		query.Set(name, primitiveString(value))
+	}
+}
+
+// pairs converts a value into the list of items that make up it's serialized form.  Object
+// entries are rendered as `key=value` when exploded and as `key,value` otherwise.
+func pairs(value interface{}, explode bool, escape bool) []string {
+	esc := func(s string) string {
+		if escape {
+			return url.PathEscape(s)
+		}
+		return s
+	}
+	switch value := value.(type) {
+	case []interface{}:
+		result := make([]string, len(value))
+		for i, item := range value {
+			result[i] = esc(primitiveString(item))
+		}
+		return result
+	case map[string]interface{}:
+		result := []string{}
+		for _, k := range sortedKeys(value) {
+			if explode {
+				result = append(result, esc(k)+"="+esc(primitiveString(value[k])))
+			} else {
+				result = append(result, esc(k), esc(primitiveString(value[k])))
+			}
+		}
+		return result
+	default:
+		return []string{esc(primitiveString(value))}
+	}
+}
+
+func primitiveString(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(value), 'f', -1, 32)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
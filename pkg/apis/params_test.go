@@ -0,0 +1,71 @@
+package apis
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerializeParam(t *testing.T) {
+	array := []interface{}{"3", "4", "5"}
+	object := map[string]interface{}{"role": "admin", "firstName": "Alex"}
+	yes, no := true, false
+
+	tests := []struct {
+		in      string
+		style   string
+		explode *bool
+		value   interface{}
+		path    string
+		query   string
+		header  string
+		cookie  string
+	}{
+		{in: "path", value: "a/b c", path: "/a%2Fb%20c"},
+		{in: "path", value: array, path: "/3,4,5"},
+		{in: "path", value: object, path: "/firstName,Alex,role,admin"},
+		{in: "path", value: object, explode: &yes, path: "/firstName=Alex,role=admin"},
+		{in: "path", style: "label", value: "5", path: "/.5"},
+		{in: "path", style: "label", value: array, path: "/.3,4,5"},
+		{in: "path", style: "label", value: array, explode: &yes, path: "/.3.4.5"},
+		{in: "path", style: "label", value: object, explode: &yes, path: "/.firstName=Alex.role=admin"},
+		{in: "path", style: "matrix", value: "5", path: "/;id=5"},
+		{in: "path", style: "matrix", value: array, path: "/;id=3,4,5"},
+		{in: "path", style: "matrix", value: array, explode: &yes, path: "/;id=3;id=4;id=5"},
+		{in: "path", style: "matrix", value: object, explode: &yes, path: "/;firstName=Alex;role=admin"},
+		{in: "query", value: 5.5, query: "id=5.5"},
+		{in: "query", value: array, query: "id=3&id=4&id=5"},
+		{in: "query", value: array, explode: &no, query: "id=3%2C4%2C5"},
+		{in: "query", value: object, query: "firstName=Alex&role=admin"},
+		{in: "query", value: object, explode: &no, query: "id=firstName%2CAlex%2Crole%2Cadmin"},
+		{in: "query", style: "spaceDelimited", explode: &no, value: array, query: "id=3+4+5"},
+		{in: "query", style: "pipeDelimited", explode: &no, value: array, query: "id=3%7C4%7C5"},
+		{in: "query", style: "deepObject", explode: &yes, value: object, query: "id%5BfirstName%5D=Alex&id%5Brole%5D=admin"},
+		{in: "header", value: array, header: "3,4,5"},
+		{in: "header", value: object, explode: &yes, header: "firstName=Alex,role=admin"},
+		{in: "cookie", value: array, explode: &no, cookie: "3,4,5"},
+	}
+	for _, test := range tests {
+		p := paramValues{
+			path:    "/{id}",
+			query:   url.Values{},
+			headers: http.Header{},
+		}
+		param := &openapi3.Parameter{Name: "id", In: test.in, Style: test.style, Explode: test.explode}
+		require.NoError(t, p.serializeParam(param, test.value))
+
+		if test.path != "" {
+			assert.Equal(t, test.path, p.path, "%+v", test)
+		}
+		assert.Equal(t, test.query, p.query.Encode(), "%+v", test)
+		assert.Equal(t, test.header, p.headers.Get("id"), "%+v", test)
+		if test.cookie != "" {
+			require.Len(t, p.cookies, 1)
+			assert.Equal(t, test.cookie, p.cookies[0].Value, "%+v", test)
+		}
+	}
+}
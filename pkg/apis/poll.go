@@ -0,0 +1,336 @@
+package apis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/chirino/graphql/resolvers"
+	"github.com/chirino/graphql/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+)
+
+// DefaultPollInterval is used when a polled operation does not configure an interval.
+const DefaultPollInterval = 10 * time.Second
+
+const pollExtension = "x-graphql-poll"
+
+// PollingOptions selects the GET operations that are also exposed as subscription fields.
+// Subscribers get the current result of the operation and then a new event every time the
+// result changes.
+type PollingOptions struct {
+	// Interval is the default interval between polls, like "30s".
+	Interval string `yaml:"interval,omitempty" json:"interval,omitempty"`
+	// Operations maps the ids of the operations to poll to their poll interval.  An empty interval
+	// uses the default one.  Operations can also be selected with the x-graphql-poll extension.
+	Operations map[string]string `yaml:"operations,omitempty" json:"operations,omitempty"`
+}
+
+// pollInterval returns the interval at which the operation should be polled, or false
+// if the operation should not be exposed as a subscription.
+func (builder *builder) pollInterval(operation *openapi3.Operation) (time.Duration, bool, error) {
+	interval, enabled := "", false
+	if raw, ok := operation.Extensions[pollExtension].(json.RawMessage); ok {
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return 0, false, errors.Errorf("invalid %s extension: %s", pollExtension, string(raw))
+		}
+		switch value := value.(type) {
+		case bool:
+			enabled = value
+		case string:
+			interval, enabled = value, true
+		case map[string]interface{}:
+			interval, _ = value["interval"].(string)
+			enabled = true
+		default:
+			return 0, false, errors.Errorf("invalid %s extension: %s", pollExtension, string(raw))
+		}
+	}
+	if value, ok := builder.options.Polling.Operations[operation.OperationID]; ok && operation.OperationID != "" {
+		enabled = true
+		if value != "" {
+			interval = value
+		}
+	}
+	if !enabled {
+		return 0, false, nil
+	}
+
+	if interval == "" {
+		interval = builder.options.Polling.Interval
+	}
+	if interval == "" {
+		return DefaultPollInterval, true, nil
+	}
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return 0, false, errors.Wrapf(err, "invalid poll interval")
+	}
+	if d <= 0 {
+		return 0, false, errors.Errorf("invalid poll interval: %s", interval)
+	}
+	return d, true, nil
+}
+
+// addSubscriptionField exposes a query field as a subscription that polls the operation.
+func (builder *builder) addSubscriptionField(operation *openapi3.Operation, interval time.Duration) error {
+	draft := builder.draft
+	queryType := builder.options.QueryType
+	subscriptionType := builder.options.SubscriptionType
+
+	fieldName := builder.operationFieldName(operation)
+	// the polled query field might be in a namespace, subscription fields never are.
+	queryType = builder.namespaceType(queryType, operation)
+	queryObject, _ := draft.Types[queryType].(*schema.Object)
+	if queryObject == nil || queryObject.Fields.Get(fieldName) == nil {
+		return errors.Errorf("query field %s.%s was not mapped", queryType, fieldName)
+	}
+	queryField := queryObject.Fields.Get(fieldName)
+
+	var rootObject *schema.Object
+	if t, ok := draft.Types[subscriptionType]; ok {
+		rootObject = t.(*schema.Object)
+	} else {
+		rootObject = &schema.Object{
+			Name: subscriptionType,
+		}
+		draft.Types[subscriptionType] = rootObject
+	}
+
+	rootObject.Fields = append(rootObject.Fields, &schema.Field{
+		Name:       queryField.Name,
+		Desc:       desc(queryField.Desc.String() + "\n\n**polled every:** `" + interval.String() + "`"),
+		Type:       queryField.Type,
+		Args:       queryField.Args,
+		Directives: queryField.Directives,
+	})
+	builder.resolvers[subscriptionType+":"+fieldName] = &pollResolver{
+		resolver: builder.resolver,
+		pollers:  builder.pollers,
+		query:    builder.resolvers[queryType+":"+fieldName],
+		interval: interval,
+		path:     operation.Extensions["path"].(string),
+		method:   operation.Extensions["method"].(string),
+	}
+	return nil
+}
+
+type pollResolver struct {
+	*resolver
+	pollers  *pollers
+	query    resolvers.Resolver
+	interval time.Duration
+	path     string
+	method   string
+}
+
+// Resolve starts a subscription which shares a poller with all the other subscribers
+// that use the same arguments and credentials.
+func (r *pollResolver) Resolve(request *resolvers.ResolveRequest, _ resolvers.Resolution) resolvers.Resolution {
+	return func() (reflect.Value, error) {
+		args, err := json.Marshal(request.Args)
+		if err != nil {
+			return reflect.Value{}, errors.WithStack(err)
+		}
+		key := pollKey{
+			path:   r.path,
+			method: r.method,
+			args:   string(args),
+		}
+
+		// Pollers poll on behalf of the first subscriber, so only share them with
+		// subscribers that present the same credentials.
+		if serverRequest, ok := request.Context.Value("*net/http.Request").(*http.Request); ok {
+			key.authorization = serverRequest.Header.Get("Authorization")
+			key.cookie = serverRequest.Header.Get("Cookie")
+		}
+
+		sub := &pollSubscriber{
+			events: make(chan pollEvent, 1),
+		}
+		ctx := request.ExecutionContext.GetContext()
+		r.pollers.subscribe(key, sub, func() *poller {
+			return r.newPoller(request)
+		})
+
+		go func() {
+			defer request.ExecutionContext.FireSubscriptionClose()
+			defer r.pollers.unsubscribe(key, sub)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event := <-sub.events:
+					if event.err != nil {
+						request.ExecutionContext.FireSubscriptionEvent(reflect.Value{}, event.err)
+						continue
+					}
+					r.fireSubscriptionEvent(request, event.value)
+				}
+			}
+		}()
+		return reflect.Value{}, nil
+	}
+}
+
+func (r *pollResolver) newPoller(request *resolvers.ResolveRequest) *poller {
+	// Polls must outlive the subscriber that started them, but still need the
+	// request values used to build the upstream request.
+	ctx, cancel := context.WithCancel(detachedContext{request.Context})
+	pollRequest := *request
+	pollRequest.Context = ctx
+
+	return &poller{
+		interval:    r.interval,
+		cancel:      cancel,
+		ctx:         ctx,
+		subscribers: map[*pollSubscriber]bool{},
+		poll: func() (reflect.Value, error) {
+			resolution := r.query.Resolve(&pollRequest, nil)
+			if resolution == nil {
+				return reflect.Value{}, errors.New("operation resolver not found")
+			}
+			return resolution()
+		},
+	}
+}
+
+type pollKey struct {
+	path          string
+	method        string
+	args          string
+	authorization string
+	cookie        string
+}
+
+type pollEvent struct {
+	value reflect.Value
+	err   error
+}
+
+type pollSubscriber struct {
+	events chan pollEvent
+}
+
+// send delivers the event, replacing any event the subscriber has not consumed yet.
+func (s *pollSubscriber) send(event pollEvent) {
+	for {
+		select {
+		case s.events <- event:
+			return
+		default:
+			select {
+			case <-s.events:
+			default:
+			}
+		}
+	}
+}
+
+type pollers struct {
+	mu     sync.Mutex
+	active map[pollKey]*poller
+}
+
+func newPollers() *pollers {
+	return &pollers{active: map[pollKey]*poller{}}
+}
+
+func (p *pollers) subscribe(key pollKey, sub *pollSubscriber, create func() *poller) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	poller := p.active[key]
+	if poller == nil {
+		poller = create()
+		poller.pollers = p
+		p.active[key] = poller
+		go poller.run()
+	}
+	poller.subscribers[sub] = true
+	if poller.last != nil {
+		sub.send(*poller.last)
+	}
+}
+
+func (p *pollers) unsubscribe(key pollKey, sub *pollSubscriber) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	poller := p.active[key]
+	if poller == nil {
+		return
+	}
+	delete(poller.subscribers, sub)
+	if len(poller.subscribers) == 0 {
+		poller.cancel()
+		delete(p.active, key)
+	}
+}
+
+type poller struct {
+	pollers     *pollers
+	interval    time.Duration
+	ctx         context.Context
+	cancel      context.CancelFunc
+	poll        resolvers.Resolution
+	subscribers map[*pollSubscriber]bool
+	last        *pollEvent
+}
+
+func (p *poller) run() {
+	var lastData []byte
+	lastErr := ""
+	for {
+		value, err := p.poll()
+		if p.ctx.Err() != nil {
+			return
+		}
+
+		// Only publish the results that differ from the previous poll.
+		changed := false
+		if err != nil {
+			changed = lastErr != err.Error()
+			lastErr, lastData = err.Error(), nil
+		} else {
+			var data []byte
+			if value.IsValid() {
+				data, _ = json.Marshal(value.Interface())
+			}
+			changed = lastErr != "" || lastData == nil || data == nil || !bytes.Equal(lastData, data)
+			lastErr, lastData = "", data
+		}
+		if changed {
+			p.publish(pollEvent{value: value, err: err})
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-time.After(p.interval):
+		}
+	}
+}
+
+func (p *poller) publish(event pollEvent) {
+	p.pollers.mu.Lock()
+	defer p.pollers.mu.Unlock()
+	p.last = &event
+	for sub := range p.subscribers {
+		sub.send(event)
+	}
+}
+
+// detachedContext keeps the values of its parent context but is never canceled.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) { return }
+func (detachedContext) Done() <-chan struct{}                   { return nil }
+func (detachedContext) Err() error                              { return nil }
+func (c detachedContext) Value(key interface{}) interface{}     { return c.parent.Value(key) }
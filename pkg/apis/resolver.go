@@ -1,11 +1,10 @@
 package apis
 
 import (
-	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
-	"github.com/chirino/graphql/inputconv"
 	"github.com/chirino/graphql/qerrors"
 	"io"
 	"io/ioutil"
@@ -16,6 +15,7 @@ import (
 	"strings"
 
 	"github.com/chirino/graphql/resolvers"
+	"github.com/chirino/graphql/schema"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/pkg/errors"
 )
@@ -27,7 +27,7 @@ type resolver struct {
 	resultConverters  map[string]Converter
 	options           Config
 	securityFunctions []func(query url.Values, headers http.Header, cookies []*http.Cookie) (url.Values, http.Header, []*http.Cookie)
-	inputConverters   inputconv.TypeConverters
+	inputConverters   typeConverters
 	next              resolvers.Resolver
 }
 
@@ -41,12 +41,46 @@ func (resolver *resolver) convert(request *resolvers.ResolveRequest, next resolv
 	return next
 }
 
+// nullOnError resolves a nullable field that fails to null and reports its error, as the GraphQL spec
+// requires, since the engine leaves failed fields out of the result.
+func nullOnError(request *resolvers.ResolveRequest, resolution resolvers.Resolution) resolvers.Resolution {
+	execution, ok := request.ExecutionContext.(interface{ AddError(err error) })
+	if _, nonNull := request.Field.Type.(*schema.NonNull); nonNull || !ok {
+		return resolution
+	}
+	return func() (reflect.Value, error) {
+		value, err := resolution()
+		if err == nil {
+			return value, nil
+		}
+		if qe, ok := err.(*qerrors.Error); ok {
+			execution.AddError(qe.WithPath(request.SelectionPath()...))
+		} else {
+			execution.AddError(qerrors.WrapError(err, err.Error()).WithPath(request.SelectionPath()...).WithStack())
+		}
+		return reflect.Value{}, nil
+	}
+}
+
+// fireSubscriptionEvent sends a value received by a subscription to the subscriber.
+func (resolver *resolver) fireSubscriptionEvent(request *resolvers.ResolveRequest, value reflect.Value) {
+	value, err := resolver.convert(request, func() (reflect.Value, error) {
+		return value, nil
+	})()
+	// Use fresh data loaders for every event so that linked fields get re-fetched.
+	ctx := context.WithValue(request.ExecutionContext.GetContext(), DataLoadersKey, dataLoaders{})
+	request.ExecutionContext.FireSubscriptionEvent(reflect.ValueOf(resolvers.ValueWithContext{
+		Value:   value,
+		Context: ctx,
+	}), err)
+}
+
 func (resolver *resolver) Resolve(request *resolvers.ResolveRequest, next resolvers.Resolution) resolvers.Resolution {
 	key := request.ParentType.String() + ":" + request.Field.Name
 	if r, ok := resolver.resolvers[key]; ok {
 		resolution := r.Resolve(request, next)
 		if resolution != nil {
-			return resolver.convert(request, resolution)
+			return nullOnError(request, resolver.convert(request, resolution))
 		}
 	}
 
@@ -134,7 +168,11 @@ func (resolver *resolver) createLinkResolver(operation *openapi3.Operation, stat
 					return reflect.Value{}, errors.Wrapf(err, "could not get link argument at path: %s", path)
 				}
 			}
-			request.Args[k] = fmt.Sprint(value)
+			if value == nil {
+				// leave it unset so that a missing required parameter gets reported.
+				continue
+			}
+			request.Args[resolver.options.fieldName(k)] = fmt.Sprint(value)
 		}
 
 		dataLoaders := request.Context.Value(DataLoadersKey).(dataLoaders)
@@ -169,7 +207,10 @@ func navigateMap(value interface{}, keys []string) (interface{}, error) {
 		if current.Kind() != reflect.Map || current.Type().Key().Kind() != reflect.String {
 			return nil, errors.New("can only navigate string keyed maps")
 		}
-		current = current.MapIndex(reflect.ValueOf(key))
+		current = resolvers.Dereference(current.MapIndex(reflect.ValueOf(key)))
+		if !current.IsValid() {
+			return nil, nil
+		}
 	}
 
 	return current.Interface(), nil
@@ -177,169 +218,250 @@ func navigateMap(value interface{}, keys []string) (interface{}, error) {
 
 func (resolver resolver) resolve(gqlRequest *resolvers.ResolveRequest, operation *openapi3.Operation, expectedStatus []int) resolvers.Resolution {
 	return func() (reflect.Value, error) {
+		return resolver.call(gqlRequest, operation, expectedStatus, nil)
+	}
+}
 
-		operationPath := operation.Extensions["path"].(string)
-		operationMethod := operation.Extensions["method"].(string)
+// call invokes the API operation.  When stream is not nil, the response body is not read, an *eventStream
+// reading it is returned instead.
+func (resolver resolver) call(gqlRequest *resolvers.ResolveRequest, operation *openapi3.Operation, expectedStatus []int, stream *streamContent) (reflect.Value, error) {
+	operationPath := operation.Extensions["path"].(string)
+	operationMethod := operation.Extensions["method"].(string)
 
-		query := url.Values{}
-		headers := http.Header{}
-		cookies := []*http.Cookie{}
+	query := url.Values{}
+	headers := http.Header{}
+	cookies := []*http.Cookie{}
 
-		ctx := gqlRequest.Context
-		if severRequest := ctx.Value("*net/http.Request"); severRequest != nil {
-			if serverRequest, ok := severRequest.(*http.Request); ok {
-				proxyHeaders(headers, serverRequest)
+	ctx := gqlRequest.Context
+	if severRequest := ctx.Value("*net/http.Request"); severRequest != nil {
+		if serverRequest, ok := severRequest.(*http.Request); ok {
+			proxyHeaders(headers, serverRequest)
 
-				cookie, err := serverRequest.Cookie("Authorization")
-				if err == nil && cookie.Value != "" {
-					headers.Set("Authorization", cookie.Value)
-				}
+			cookie, err := serverRequest.Cookie("Authorization")
+			if err == nil && cookie.Value != "" {
+				headers.Set("Authorization", cookie.Value)
 			}
 		}
+	}
 
-		if resolver.options.APIBase.BearerToken != "" && headers.Get("Authorization") == "" {
-			headers.Set("Authorization", "Bearer "+resolver.options.APIBase.BearerToken)
-		}
-
-		for _, param := range operation.Parameters {
-			param := param.Value
-			qlid := sanitizeName(param.Name)
-			value, found := gqlRequest.Args[qlid]
-			switch param.In {
-			case "path":
-				if !found { // all path params are required.
-					panic("required path parameter not set: " + qlid)
-				}
-				operationPath = strings.ReplaceAll(operationPath, fmt.Sprintf("{%s}", param.Name), fmt.Sprintf("%v", value))
+	if resolver.options.APIBase.BearerToken != "" && headers.Get("Authorization") == "" {
+		headers.Set("Authorization", "Bearer "+resolver.options.APIBase.BearerToken)
+	}
 
-			case "query":
-				if param.Required && !found {
-					panic("required query parameter not set: " + qlid)
-				}
-				if found {
-					query.Set(param.Name, fmt.Sprintf("%v", value))
-				}
-			case "header":
-				if param.Name == "Accept-Encoding" {
-					// the go http client automatically handles gzip decoding... manually setting the
-					// the header disables this feature... so don't set it.
-					continue
-				}
-				if param.Required && !found {
-					panic("required header parameter not set: " + qlid)
-				}
-				if found {
-					headers.Set(param.Name, fmt.Sprintf("%v", value))
-				}
+	if resolver.options.ValidateInputs || resolver.options.Mock.Enabled {
+		if err := resolver.validateArgs(gqlRequest, operation); err != nil {
+			return reflect.Value{}, err
+		}
+	}
 
-			case "cookie":
-				cookies = append(cookies, &http.Cookie{
-					Name:  param.Name,
-					Value: fmt.Sprintf("%v", value),
-				})
-				// TODO: consider how to best handle these...
+	params := paramValues{
+		path:    operationPath,
+		query:   query,
+		headers: headers,
+		cookies: cookies,
+	}
+	for _, param := range operation.Parameters {
+		param := param.Value
+		qlid := resolver.options.fieldName(param.Name)
+		value, found := gqlRequest.Args[qlid]
+		if param.In == "header" && param.Name == "Accept-Encoding" {
+			// the go http client automatically handles gzip decoding... manually setting the
+			// the header disables this feature... so don't set it.
+			continue
+		}
+		if arg := gqlRequest.Field.Args.Get(qlid); !found && arg != nil && arg.Default != nil {
+			value, found = arg.Default.Evaluate(nil), true
+		}
+		if !found || value == nil {
+			// all path params are required.
+			if param.Required || param.In == "path" {
+				return reflect.Value{}, missingParameterError(param, qlid)
 			}
+			continue
 		}
 
-		headers.Set("Content-Type", "application/json")
-		headers.Set("Accept", "application/json")
-
-		apiURL, err := url.Parse(resolver.options.APIBase.URL)
+		// convert back things like additional property wrappers to maps...
+		var err error
+		if arg := gqlRequest.Field.Args.Get(qlid); arg != nil {
+			value, err = resolver.inputConverters.Convert(arg.Type, value, qlid)
+			if err != nil {
+				return reflect.Value{}, errors.WithStack(err)
+			}
+		}
+		err = params.serializeParam(param, value)
 		if err != nil {
-			return reflect.Value{}, errors.WithStack(err)
+			return reflect.Value{}, err
 		}
+	}
+	cookies = params.cookies
 
-		apiURL.Path += operationPath
+	headers.Set("Content-Type", "application/json")
+	if stream != nil && stream.mediaType != "" {
+		headers.Set("Accept", stream.accept())
+	} else {
+		headers.Set("Accept", resolver.acceptHeader(operation, expectedStatus))
+	}
 
-		for _, f := range resolver.securityFunctions {
-			query, headers, cookies = f(query, headers, cookies)
-		}
+	baseURL := resolver.options.APIBase.URL
+	if server, ok := operation.Extensions["server"].(string); ok {
+		// the operation or its path has its own servers.
+		baseURL = server
+	}
+	apiURL, err := url.Parse(baseURL)
+	if err != nil {
+		return reflect.Value{}, errors.WithStack(err)
+	}
+
+	// the serialized path parameters are already percent encoded.
+	apiURL.RawPath = apiURL.EscapedPath() + params.path
+	apiURL.Path, err = url.PathUnescape(apiURL.RawPath)
+	if err != nil {
+		return reflect.Value{}, errors.WithStack(err)
+	}
 
-		for _, cookie := range cookies {
-			if v := cookie.String(); v != "" {
-				headers.Add("Cookie", v)
+	if operation.Extensions["download"] == true && stream == nil {
+		// Let the client download the binary data directly from the API, the operation needs no credentials.
+		downloadURL := *apiURL
+		downloadURL.RawQuery = query.Encode()
+		for _, status := range expectedStatus {
+			opResponse := operation.Responses.Get(status)
+			if opResponse != nil && opResponse.Value.Content != nil {
+				if _, _, format := resolver.responseContent(opResponse.Value.Content); format == binaryResponse {
+					return reflect.ValueOf(downloadURL.String()), nil
+				}
 			}
 		}
-		apiURL.RawQuery = query.Encode()
+	}
+
+	for _, f := range resolver.securityFunctions {
+		query, headers, cookies = f(query, headers, cookies)
+	}
 
-		client := resolver.options.APIBase.Client
-		if client == nil {
-			client = &http.Client{Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: resolver.options.APIBase.InsecureClient},
-			}}
+	for _, cookie := range cookies {
+		if v := cookie.String(); v != "" {
+			headers.Add("Cookie", v)
 		}
+	}
+	apiURL.RawQuery = query.Encode()
 
-		var body io.Reader = nil
-		if operation.RequestBody != nil {
-			content := operation.RequestBody.Value.Content.Get("application/json")
-			if content != nil {
+	client := resolver.options.APIBase.Client
+	if client == nil {
+		client = &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: resolver.options.APIBase.InsecureClient},
+		}}
+	}
 
-				v, err := resolver.inputConverters.Convert(gqlRequest.Field.Args.Get("body").Type, gqlRequest.Args["body"], "body")
-				if err != nil {
-					return reflect.Value{}, errors.WithStack(err)
-				}
+	var body io.Reader = nil
+	var bodyValue interface{}
+	if mediaType, content := requestBodyContent(operation); content != nil {
+		if value, found := gqlRequest.Args["body"]; found && value != nil {
+			// don't hold on to uploads the request does not end up consuming.
+			defer closeUploads(value)
+
+			v, err := resolver.inputConverters.Convert(gqlRequest.Field.Args.Get("body").Type, value, "body")
+			if err != nil {
+				return reflect.Value{}, errors.WithStack(err)
+			}
+
+			bodyValue = v
 
-				data, err := json.Marshal(v)
+			// the mock mode does not call the API, nothing would consume the encoded body.
+			if !resolver.options.Mock.Enabled {
+				var contentType string
+				body, contentType, err = encodeRequestBody(mediaType, content, v)
 				if err != nil {
-					return reflect.Value{}, errors.WithStack(err)
+					return reflect.Value{}, err
 				}
-
-				body = bytes.NewReader(data)
+				if closer, ok := body.(io.Closer); ok {
+					// stops the goroutine writing a multipart body when the request fails before reading it.
+					defer closer.Close()
+				}
+				headers.Set("Content-Type", contentType)
 			}
 		}
+	}
 
-		request, err := http.NewRequestWithContext(ctx, operationMethod, apiURL.String(), body)
+	if resolver.options.Mock.Enabled {
+		result, err := resolver.mock(operationMethod, apiURL.String(), operation, expectedStatus, stream, gqlRequest.Args, bodyValue)
 		if err != nil {
-			return reflect.Value{}, errors.WithStack(err)
+			return reflect.Value{}, err
 		}
+		return reflect.ValueOf(result), nil
+	}
 
-		if operation.RequestBody != nil {
-			content := operation.RequestBody.Value.Content.Get("application/json")
-			if content != nil {
-			}
-		}
+	request, err := http.NewRequestWithContext(ctx, operationMethod, apiURL.String(), body)
+	if err != nil {
+		return reflect.Value{}, errors.WithStack(err)
+	}
 
-		request.Header = headers
-		resp, err := client.Do(request)
-		if err != nil {
-			return reflect.Value{}, errors.WithStack(err)
+	request.Header = headers
+	resp, err := client.Do(request)
+	if err != nil {
+		return reflect.Value{}, errors.WithStack(err)
+	}
+	for _, expected := range expectedStatus {
+		if expected == resp.StatusCode && stream != nil {
+			// the subscription reads and closes the body.
+			return reflect.ValueOf(newEventStream(resp.Body, stream.format)), nil
 		}
-		defer resp.Body.Close()
-
-		for _, expected := range expectedStatus {
-			if expected == resp.StatusCode {
+	}
+	defer resp.Body.Close()
 
-				opResponse := operation.Responses.Get(resp.StatusCode)
+	for _, expected := range expectedStatus {
+		if expected == resp.StatusCode {
 
-				// Handle to NO_CONTENT case...
-				if opResponse.Value.Content == nil {
-					return reflect.ValueOf(""), nil
-				}
+			opResponse := operation.Responses.Get(resp.StatusCode)
 
-				var result interface{}
-				err := json.NewDecoder(resp.Body).Decode(&result)
-				if err != nil {
-					return reflect.Value{}, errors.WithStack(err)
-				}
-				return reflect.ValueOf(result), nil
+			// Handle to NO_CONTENT case...
+			if opResponse.Value.Content == nil {
+				return reflect.ValueOf(""), nil
 			}
-		}
 
-		// All other statuses are considered errors...
-		extensions := map[string]interface{}{}
-		extensions["status"] = resp.StatusCode
-		if resp.Header.Get("Content-Type") == "application/json" {
-			response := map[string]interface{}{}
-			err := json.NewDecoder(resp.Body).Decode(&response)
+			_, content, format := resolver.responseContent(opResponse.Value.Content)
+			result, err := decodeResponse(resp.Body, content, format)
 			if err != nil {
 				return reflect.Value{}, err
 			}
-			extensions["response"] = response
-		} else {
-			all, _ := ioutil.ReadAll(resp.Body)
-			extensions["response"] = string(all)
+			return reflect.ValueOf(result), nil
+		}
+	}
+
+	// All other statuses are considered errors...
+	extensions := map[string]interface{}{}
+	extensions["status"] = resp.StatusCode
+	if isJSONMediaType(resp.Header.Get("Content-Type")) {
+		response := map[string]interface{}{}
+		err := json.NewDecoder(resp.Body).Decode(&response)
+		if err != nil {
+			return reflect.Value{}, err
 		}
+		extensions["response"] = response
+	} else {
+		all, _ := ioutil.ReadAll(resp.Body)
+		extensions["response"] = string(all)
+	}
+
+	return reflect.Value{}, qerrors.Errorf("http response status code: %d", resp.StatusCode).WithExtensions(extensions).WithStack()
+}
+
+func missingParameterError(param *openapi3.Parameter, qlid string) error {
+	return qerrors.Errorf("required %s parameter not set: %s", param.In, qlid).
+		WithExtensions(map[string]interface{}{
+			"argument": qlid,
+		})
+}
 
-		return reflect.Value{}, qerrors.Errorf("http response status code: %d", resp.StatusCode).WithExtensions(extensions).WithStack()
+func closeUploads(value interface{}) {
+	switch value := value.(type) {
+	case *Upload:
+		_ = value.Close()
+	case map[string]interface{}:
+		for _, v := range value {
+			closeUploads(v)
+		}
+	case []interface{}:
+		for _, v := range value {
+			closeUploads(v)
+		}
 	}
 }
@@ -0,0 +1,281 @@
+package apis
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+)
+
+type responseFormat int
+
+const (
+	jsonResponse responseFormat = iota
+	xmlResponse
+	textResponse
+	binaryResponse
+)
+
+const (
+	BinaryResponsesBase64 = "base64"
+	BinaryResponsesURL    = "url"
+)
+
+// downloadable returns true when the binary response of the operation can be returned as the URL the
+// client downloads it from.  That's only the case for GET operations that need no credentials, since
+// the credentials of the gateway would leak to the client, and the header ones can't be in the URL.
+func (builder *builder) downloadable(operation *openapi3.Operation) bool {
+	if builder.options.BinaryResponses != BinaryResponsesURL || operation.Extensions["method"] != "GET" {
+		return false
+	}
+	if builder.options.APIBase.BearerToken != "" || len(builder.securityFunctions) > 0 {
+		return false
+	}
+	security := builder.security
+	if operation.Security != nil {
+		security = *operation.Security
+	}
+	if requiresCredentials(security) {
+		return false
+	}
+	for _, param := range operation.Parameters {
+		if param.Value != nil && param.Value.In != "path" && param.Value.In != "query" {
+			return false
+		}
+	}
+	return true
+}
+
+// requiresCredentials returns false if there are no security requirements, or if one of the
+// alternatives is empty, which makes the security optional.
+func requiresCredentials(security openapi3.SecurityRequirements) bool {
+	for _, requirement := range security {
+		if len(requirement) == 0 {
+			return false
+		}
+	}
+	return len(security) > 0
+}
+
+// responseContent picks the media type of a response that we will map to a GraphQL type.  JSON media
+// types are preferred, then XML ones (if XML decoding is enabled), then text and finally binary ones.
+func (resolver *resolver) responseContent(content openapi3.Content) (string, *openapi3.MediaType, responseFormat) {
+	var mediaTypes []string
+	for mediaType := range content {
+		// streamed responses are only read by subscriptions.
+		if !isStreamMediaType(mediaType) {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+	}
+	sort.Strings(mediaTypes)
+
+	best := ""
+	bestFormat := binaryResponse
+	for _, mediaType := range mediaTypes {
+		format := resolver.mediaTypeFormat(mediaType, content[mediaType])
+		if best == "" || format < bestFormat || (format == bestFormat && mediaType == "application/json") {
+			best = mediaType
+			bestFormat = format
+		}
+	}
+	if best == "" {
+		return "", nil, jsonResponse
+	}
+	return best, content[best], bestFormat
+}
+
+func (resolver *resolver) mediaTypeFormat(mediaType string, content *openapi3.MediaType) responseFormat {
+	parsed, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		parsed = mediaType
+	}
+	parsed = strings.ToLower(parsed)
+	switch {
+	case isJSONMediaType(parsed):
+		return jsonResponse
+	case parsed == "*/*" || parsed == "application/*":
+		// wildcards are treated as json if we know what the schema looks like.
+		if content != nil && content.Schema != nil {
+			return jsonResponse
+		}
+		return binaryResponse
+	case parsed == "application/xml" || parsed == "text/xml" || strings.HasSuffix(parsed, "+xml"):
+		if resolver.options.XMLResponses && content != nil && content.Schema != nil {
+			return xmlResponse
+		}
+		return textResponse
+	case strings.HasPrefix(parsed, "text/"):
+		return textResponse
+	default:
+		return binaryResponse
+	}
+}
+
+// acceptHeader computes the Accept header to use when calling an operation.
+func (resolver *resolver) acceptHeader(operation *openapi3.Operation, expectedStatus []int) string {
+	accept := []string{}
+	seen := map[string]bool{}
+	for _, status := range expectedStatus {
+		response := operation.Responses.Get(status)
+		if response == nil || response.Value == nil || response.Value.Content == nil {
+			continue
+		}
+		mediaType, _, _ := resolver.responseContent(response.Value.Content)
+		if mediaType != "" && !seen[mediaType] {
+			seen[mediaType] = true
+			accept = append(accept, mediaType)
+		}
+	}
+	if len(accept) == 0 {
+		return "application/json"
+	}
+	return strings.Join(accept, ", ")
+}
+
+// decodeResponse converts the response body to a value that the GraphQL field resolvers can traverse.
+func decodeResponse(body io.Reader, content *openapi3.MediaType, format responseFormat) (interface{}, error) {
+	switch format {
+	case textResponse:
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return string(data), nil
+	case binaryResponse:
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return base64.StdEncoding.EncodeToString(data), nil
+	case xmlResponse:
+		node := &xmlNode{}
+		err := xml.NewDecoder(body).Decode(node)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return node.toJSON(content.Schema), nil
+	default:
+		var result interface{}
+		err := json.NewDecoder(body).Decode(&result)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return result, nil
+	}
+}
+
+// xmlNode is a generic XML element tree that can then be converted to JSON like values.
+type xmlNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Text     string     `xml:",chardata"`
+	Children []*xmlNode `xml:",any"`
+}
+
+// toJSON converts the node to the JSON like value described by the schema.
+func (n *xmlNode) toJSON(ref *openapi3.SchemaRef) interface{} {
+	if ref == nil || ref.Value == nil {
+		return strings.TrimSpace(n.Text)
+	}
+	s := ref.Value
+	switch s.Type {
+	case "array":
+		result := []interface{}{}
+		for _, child := range n.Children {
+			result = append(result, child.toJSON(s.Items))
+		}
+		return result
+	case "object", "":
+		if !hasProperties(s, map[*openapi3.Schema]bool{}) {
+			return strings.TrimSpace(n.Text)
+		}
+		result := map[string]interface{}{}
+		n.addProperties(s, result)
+		return result
+	default:
+		return xmlScalar(strings.TrimSpace(n.Text), s.Type)
+	}
+}
+
+func (n *xmlNode) addProperties(s *openapi3.Schema, result map[string]interface{}) {
+	for _, refs := range []openapi3.SchemaRefs{s.AllOf, s.AnyOf, s.OneOf} {
+		for _, ref := range refs {
+			n.addProperties(ref.Value, result)
+		}
+	}
+	for name, prop := range s.Properties {
+		xmlName, attribute, wrapped := xmlOptions(prop.Value, name)
+		if attribute {
+			for _, attr := range n.Attrs {
+				if attr.Name.Local == xmlName {
+					result[name] = xmlScalar(attr.Value, prop.Value.Type)
+				}
+			}
+			continue
+		}
+
+		var matches []*xmlNode
+		for _, child := range n.Children {
+			if child.XMLName.Local == xmlName {
+				matches = append(matches, child)
+			}
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		if prop.Value.Type == "array" {
+			items := []interface{}{}
+			if wrapped {
+				for _, child := range matches[0].Children {
+					items = append(items, child.toJSON(prop.Value.Items))
+				}
+			} else {
+				for _, child := range matches {
+					items = append(items, child.toJSON(prop.Value.Items))
+				}
+			}
+			result[name] = items
+		} else {
+			result[name] = matches[0].toJSON(prop)
+		}
+	}
+}
+
+// xmlOptions reads the `xml` object of a schema.
+func xmlOptions(s *openapi3.Schema, name string) (xmlName string, attribute bool, wrapped bool) {
+	xmlName = name
+	if options, ok := s.XML.(map[string]interface{}); ok {
+		if v, ok := options["name"].(string); ok && v != "" {
+			xmlName = v
+		}
+		attribute, _ = options["attribute"].(bool)
+		wrapped, _ = options["wrapped"].(bool)
+	}
+	return
+}
+
+func xmlScalar(text string, schemaType string) interface{} {
+	switch schemaType {
+	case "integer":
+		if v, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return v
+		}
+	case "number":
+		if v, err := strconv.ParseFloat(text, 64); err == nil {
+			return v
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(text); err == nil {
+			return v
+		}
+	}
+	return text
+}
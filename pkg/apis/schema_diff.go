@@ -0,0 +1,313 @@
+package apis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chirino/graphql/schema"
+	"github.com/pkg/errors"
+)
+
+// Criticality ranks the impact of a schema change on the clients of the previous schema.
+type Criticality int
+
+const (
+	// CriticalitySafe changes don't affect existing clients.
+	CriticalitySafe Criticality = iota
+	// CriticalityDangerous changes keep existing queries valid, but may change the results clients get.
+	CriticalityDangerous
+	// CriticalityBreaking changes make existing queries invalid.
+	CriticalityBreaking
+)
+
+var criticalityNames = []string{"safe", "dangerous", "breaking"}
+
+func (c Criticality) String() string {
+	if int(c) < len(criticalityNames) {
+		return criticalityNames[c]
+	}
+	return fmt.Sprintf("Criticality(%d)", int(c))
+}
+
+func (c Criticality) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *Criticality) UnmarshalText(text []byte) error {
+	criticality, err := ParseCriticality(string(text))
+	if err != nil {
+		return err
+	}
+	*c = criticality
+	return nil
+}
+
+// ParseCriticality parses a criticality name: "safe", "dangerous" or "breaking".
+func ParseCriticality(name string) (Criticality, error) {
+	for i, n := range criticalityNames {
+		if strings.EqualFold(n, name) {
+			return Criticality(i), nil
+		}
+	}
+	return 0, errors.Errorf("invalid criticality: %s", name)
+}
+
+// SchemaChange describes a difference between two GraphQL schemas.
+type SchemaChange struct {
+	Criticality Criticality `json:"criticality"`
+	// Path is the changed schema element, like `Query.listPets(limit)`.
+	Path        string `json:"path"`
+	Description string `json:"description"`
+}
+
+// DiffSchemas compares two GraphQL schemas and classifies the changes made to the previous one.
+func DiffSchemas(previous *schema.Schema, next *schema.Schema) []SchemaChange {
+	d := &schemaDiff{}
+	for _, op := range []schema.OperationType{schema.Query, schema.Mutation, schema.Subscription} {
+		previousType, nextType := previous.EntryPoints[op], next.EntryPoints[op]
+		if previousType != nil && (nextType == nil || nextType.TypeName() != previousType.TypeName()) {
+			d.add(CriticalityBreaking, string(op), "%s root type %s was changed or removed", op, previousType.TypeName())
+		}
+	}
+	for _, name := range sortedTypeNames(previous.Types) {
+		previousType := previous.Types[name]
+		nextType, ok := next.Types[name]
+		if !ok {
+			d.add(CriticalityBreaking, name, "type %s was removed", name)
+			continue
+		}
+		if previousType.Kind() != nextType.Kind() {
+			d.add(CriticalityBreaking, name, "type %s changed from %s to %s", name, previousType.Kind(), nextType.Kind())
+			continue
+		}
+		switch previousType := previousType.(type) {
+		case *schema.Object:
+			nextType := nextType.(*schema.Object)
+			d.diffFields(name, previousType.Fields, nextType.Fields)
+			d.diffNames(name, "interface", interfaceNames(previousType.Interfaces), interfaceNames(nextType.Interfaces), CriticalityDangerous)
+		case *schema.Interface:
+			d.diffFields(name, previousType.Fields, nextType.(*schema.Interface).Fields)
+		case *schema.InputObject:
+			d.diffInputFields(name, previousType.Fields, nextType.(*schema.InputObject).Fields)
+		case *schema.Enum:
+			d.diffNames(name, "enum value", enumValueNames(previousType.Values), enumValueNames(nextType.(*schema.Enum).Values), CriticalityDangerous)
+		case *schema.Union:
+			d.diffNames(name, "union member", objectNames(previousType.PossibleTypes), objectNames(nextType.(*schema.Union).PossibleTypes), CriticalityDangerous)
+		}
+	}
+	for _, name := range sortedTypeNames(next.Types) {
+		if _, ok := previous.Types[name]; !ok {
+			d.add(CriticalitySafe, name, "type %s was added", name)
+		}
+	}
+	return d.changes
+}
+
+type schemaDiff struct {
+	changes []SchemaChange
+}
+
+func (d *schemaDiff) add(criticality Criticality, path string, format string, v ...interface{}) {
+	d.changes = append(d.changes, SchemaChange{
+		Criticality: criticality,
+		Path:        path,
+		Description: fmt.Sprintf(format, v...),
+	})
+}
+
+func (d *schemaDiff) diffFields(typeName string, previous schema.FieldList, next schema.FieldList) {
+	for _, field := range previous {
+		path := typeName + "." + field.Name
+		nextField := next.Get(field.Name)
+		if nextField == nil {
+			d.add(CriticalityBreaking, path, "field %s was removed", path)
+			continue
+		}
+		if field.Type.String() != nextField.Type.String() {
+			if isSafeOutputChange(field.Type, nextField.Type) {
+				d.add(CriticalitySafe, path, "field %s changed type from %s to %s", path, field.Type, nextField.Type)
+			} else {
+				d.add(CriticalityBreaking, path, "field %s changed type from %s to %s", path, field.Type, nextField.Type)
+			}
+		}
+		if field.Directives.Get("deprecated") == nil && nextField.Directives.Get("deprecated") != nil {
+			d.add(CriticalitySafe, path, "field %s was deprecated", path)
+		}
+		d.diffArgs(path, field.Args, nextField.Args)
+	}
+	for _, field := range next {
+		if previous.Get(field.Name) == nil {
+			d.add(CriticalitySafe, typeName+"."+field.Name, "field %s.%s was added", typeName, field.Name)
+		}
+	}
+}
+
+func (d *schemaDiff) diffArgs(fieldPath string, previous schema.InputValueList, next schema.InputValueList) {
+	for _, arg := range previous {
+		path := fieldPath + "(" + arg.Name + ")"
+		nextArg := next.Get(arg.Name)
+		if nextArg == nil {
+			d.add(CriticalityBreaking, path, "argument %s was removed", path)
+			continue
+		}
+		d.diffInputValue(path, "argument", arg, nextArg)
+	}
+	for _, arg := range next {
+		if previous.Get(arg.Name) == nil {
+			path := fieldPath + "(" + arg.Name + ")"
+			if isRequired(arg) {
+				d.add(CriticalityBreaking, path, "required argument %s was added", path)
+			} else {
+				d.add(CriticalityDangerous, path, "optional argument %s was added", path)
+			}
+		}
+	}
+}
+
+func (d *schemaDiff) diffInputFields(typeName string, previous schema.InputValueList, next schema.InputValueList) {
+	for _, field := range previous {
+		path := typeName + "." + field.Name
+		nextField := next.Get(field.Name)
+		if nextField == nil {
+			d.add(CriticalityBreaking, path, "input field %s was removed", path)
+			continue
+		}
+		d.diffInputValue(path, "input field", field, nextField)
+	}
+	for _, field := range next {
+		if previous.Get(field.Name) == nil {
+			path := typeName + "." + field.Name
+			if isRequired(field) {
+				d.add(CriticalityBreaking, path, "required input field %s was added", path)
+			} else {
+				d.add(CriticalityDangerous, path, "optional input field %s was added", path)
+			}
+		}
+	}
+}
+
+func (d *schemaDiff) diffInputValue(path string, kind string, previous *schema.InputValue, next *schema.InputValue) {
+	if previous.Type.String() != next.Type.String() {
+		if isSafeInputChange(previous.Type, next.Type) {
+			d.add(CriticalitySafe, path, "%s %s changed type from %s to %s", kind, path, previous.Type, next.Type)
+		} else {
+			d.add(CriticalityBreaking, path, "%s %s changed type from %s to %s", kind, path, previous.Type, next.Type)
+		}
+	}
+	if literalString(previous.Default) != literalString(next.Default) {
+		d.add(CriticalityDangerous, path, "%s %s default value changed from %s to %s", kind, path, literalString(previous.Default), literalString(next.Default))
+	}
+}
+
+// diffNames compares the members of a type, like the values of an enum.  Removing one is a breaking
+// change, adding one has the given criticality.
+func (d *schemaDiff) diffNames(typeName string, kind string, previous []string, next []string, added Criticality) {
+	nextNames := map[string]bool{}
+	for _, name := range next {
+		nextNames[name] = true
+	}
+	previousNames := map[string]bool{}
+	for _, name := range previous {
+		previousNames[name] = true
+		if !nextNames[name] {
+			d.add(CriticalityBreaking, typeName+"."+name, "%s %s was removed from %s", kind, name, typeName)
+		}
+	}
+	for _, name := range next {
+		if !previousNames[name] {
+			d.add(added, typeName+"."+name, "%s %s was added to %s", kind, name, typeName)
+		}
+	}
+}
+
+// isSafeOutputChange returns true if clients expecting the previous result type can handle the next one,
+// which is the case when the next one only adds non-null wrappers.
+func isSafeOutputChange(previous schema.Type, next schema.Type) bool {
+	switch previous := previous.(type) {
+	case *schema.List:
+		switch next := next.(type) {
+		case *schema.List:
+			return isSafeOutputChange(previous.OfType, next.OfType)
+		case *schema.NonNull:
+			return isSafeOutputChange(previous, next.OfType)
+		}
+		return false
+	case *schema.NonNull:
+		if next, ok := next.(*schema.NonNull); ok {
+			return isSafeOutputChange(previous.OfType, next.OfType)
+		}
+		return false
+	}
+	if nonNull, ok := next.(*schema.NonNull); ok {
+		next = nonNull.OfType
+	}
+	return previous.String() == next.String()
+}
+
+// isSafeInputChange returns true if the values clients sent for the previous input type are valid for
+// the next one, which is the case when the next one only removes non-null wrappers.
+func isSafeInputChange(previous schema.Type, next schema.Type) bool {
+	switch previous := previous.(type) {
+	case *schema.List:
+		if next, ok := next.(*schema.List); ok {
+			return isSafeInputChange(previous.OfType, next.OfType)
+		}
+		return false
+	case *schema.NonNull:
+		if next, ok := next.(*schema.NonNull); ok {
+			return isSafeInputChange(previous.OfType, next.OfType)
+		}
+		return isSafeInputChange(previous.OfType, next)
+	}
+	return previous.String() == next.String()
+}
+
+func isRequired(value *schema.InputValue) bool {
+	_, nonNull := value.Type.(*schema.NonNull)
+	return nonNull && value.Default == nil
+}
+
+func literalString(l schema.Literal) string {
+	if l == nil {
+		return "none"
+	}
+	return l.String()
+}
+
+func sortedTypeNames(types map[string]schema.NamedType) []string {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		// skip the introspection types.
+		if strings.HasPrefix(name, "__") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func interfaceNames(list schema.InterfaceList) []string {
+	var names []string
+	for _, i := range list {
+		names = append(names, i.Name)
+	}
+	return names
+}
+
+func enumValueNames(list []*schema.EnumValue) []string {
+	var names []string
+	for _, v := range list {
+		names = append(names, v.Name)
+	}
+	return names
+}
+
+func objectNames(list []*schema.Object) []string {
+	var names []string
+	for _, o := range list {
+		names = append(names, o.Name)
+	}
+	return names
+}
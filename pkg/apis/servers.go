@@ -0,0 +1,138 @@
+package apis
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+)
+
+// ServerOptions selects the server of the openapi document that API requests are sent to when the api
+// URL is not configured, and sets the values of the server variables.
+type ServerOptions struct {
+	// Index selects the server by its position in the servers of the openapi document that have a URL, the
+	// first one is 0.
+	Index int `yaml:"index,omitempty" json:"index,omitempty"`
+	// Description selects the server with this description instead, it is matched case insensitively.
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// Variables overrides the default values of the server variables, like `region: eu`.
+	Variables map[string]string `yaml:"variables,omitempty" json:"variables,omitempty"`
+}
+
+// selectServer returns the server selected by the options, or nil if the document has no servers.  The
+// servers without a URL are skipped.
+func selectServer(servers openapi3.Servers, options ServerOptions) (*openapi3.Server, error) {
+	var usable openapi3.Servers
+	for _, server := range servers {
+		if server != nil && server.URL != "" {
+			usable = append(usable, server)
+		}
+	}
+	servers = usable
+	if options.Description != "" {
+		for _, server := range servers {
+			if strings.EqualFold(server.Description, options.Description) {
+				return server, nil
+			}
+		}
+		return nil, errors.Errorf("the openapi document has no server with the description: %s", options.Description)
+	}
+	if options.Index < 0 || options.Index >= len(servers) && options.Index != 0 {
+		return nil, errors.Errorf("invalid server index %d, the openapi document has %d servers", options.Index, len(servers))
+	}
+	if len(servers) == 0 {
+		return nil, nil
+	}
+	return servers[options.Index], nil
+}
+
+// operationServer returns the server of the operation or of its path, or nil when they use the servers of
+// the openapi document.  The server with the configured description is preferred, the first one otherwise.
+func operationServer(pathItem *openapi3.PathItem, operation *openapi3.Operation, options ServerOptions) *openapi3.Server {
+	servers := pathItem.Servers
+	if operation.Servers != nil && len(*operation.Servers) > 0 {
+		servers = *operation.Servers
+	}
+	if server, err := selectServer(servers, ServerOptions{Description: options.Description}); err == nil {
+		return server
+	}
+	server, _ := selectServer(servers, ServerOptions{})
+	return server
+}
+
+// ServerURL returns the URL of a server with its variables replaced by their value in variables, or by
+// their default value.  Relative URLs are resolved against base, they are an error if base is nil.
+func ServerURL(server *openapi3.Server, variables map[string]string, base *url.URL) (string, error) {
+	if server == nil {
+		return "", errors.New("the server is not defined")
+	}
+	result := strings.Builder{}
+	template := server.URL
+	for {
+		start := strings.Index(template, "{")
+		if start < 0 {
+			result.WriteString(template)
+			break
+		}
+		end := strings.Index(template[start:], "}")
+		if end < 0 {
+			return "", errors.Errorf("unterminated variable in the server URL: %s", server.URL)
+		}
+		name := template[start+1 : start+end]
+		result.WriteString(template[:start])
+		template = template[start+end+1:]
+
+		declared := server.Variables[name]
+		value, ok := variables[name]
+		switch {
+		case ok && declared != nil && len(declared.Enum) > 0 && !containsString(declared.Enum, value):
+			return "", errors.Errorf("invalid value of the %s server variable: %s, expected one of: %s", name, value, strings.Join(declared.Enum, ", "))
+		case ok:
+		case declared != nil:
+			value = declared.Default
+		default:
+			return "", errors.Errorf("the %s variable of the server URL %s has no value", name, server.URL)
+		}
+		result.WriteString(value)
+	}
+
+	u, err := url.Parse(result.String())
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if u.IsAbs() {
+		return u.String(), nil
+	}
+	if base == nil {
+		return "", errors.Errorf("the relative server URL %s can only be resolved for a remote openapi document, configure the api url", u)
+	}
+	return base.ResolveReference(u).String(), nil
+}
+
+// serverBase returns the URL the relative servers of paths and operations are resolved against: the location
+// of a remote openapi document, or the URL of the selected server of the document.
+func serverBase(options Config) *url.URL {
+	if base := remoteURL(options.Openapi.URL); base != nil {
+		return base
+	}
+	return remoteURL(options.APIBase.URL)
+}
+
+// remoteURL returns the parsed location if it is a remote URL, or nil.
+func remoteURL(location string) *url.URL {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil
+	}
+	return u
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
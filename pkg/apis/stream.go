@@ -0,0 +1,320 @@
+package apis
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"mime"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chirino/graphql/resolvers"
+	"github.com/chirino/graphql/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+)
+
+const (
+	StreamFormatNDJSON = "ndjson"
+	StreamFormatSSE    = "sse"
+)
+
+type streamFormat int
+
+const (
+	ndjsonStream streamFormat = iota
+	sseStream
+	// watchStream is a kubernetes watch: newline delimited {"type": "...", "object": {...}} events.
+	watchStream
+)
+
+// StreamingOptions configures the operations that stream their responses.  Operations with
+// text/event-stream, newline delimited JSON or kubernetes watch (;stream=watch) responses are
+// detected automatically.
+type StreamingOptions struct {
+	// Operations maps the ids of operations that stream their response to the format of
+	// the stream: "ndjson" or "sse".
+	Operations map[string]string `yaml:"operations,omitempty" json:"operations,omitempty"`
+}
+
+// streamContent describes the streaming response of an operation.
+type streamContent struct {
+	mediaType string
+	content   *openapi3.MediaType
+	format    streamFormat
+	status    []int
+	// only is true when the operation can't be called without streaming the response.
+	only bool
+}
+
+// accept returns the Accept header to use when calling the operation.
+func (s *streamContent) accept() string {
+	if s.format == watchStream {
+		// kubernetes streams watches when the watch parameter is set, it does not
+		// need (or like) the stream=watch media type parameter.
+		if mediaType, _, err := mime.ParseMediaType(s.mediaType); err == nil {
+			return mediaType
+		}
+	}
+	return s.mediaType
+}
+
+func streamMediaTypeFormat(mediaType string) (streamFormat, bool) {
+	parsed, params, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return 0, false
+	}
+	parsed = strings.ToLower(parsed)
+	switch {
+	case params["stream"] == "watch" && isJSONMediaType(parsed):
+		return watchStream, true
+	case parsed == "text/event-stream":
+		return sseStream, true
+	case parsed == "application/x-ndjson", parsed == "application/ndjson",
+		parsed == "application/jsonl", parsed == "application/x-jsonlines",
+		parsed == "application/stream+json":
+		return ndjsonStream, true
+	}
+	return 0, false
+}
+
+func isStreamMediaType(mediaType string) bool {
+	_, ok := streamMediaTypeFormat(mediaType)
+	return ok
+}
+
+// streamingResponse finds the streaming response of an operation, it returns nil if the operation
+// does not stream its response.
+func (builder *builder) streamingResponse(operation *openapi3.Operation) (*streamContent, error) {
+	configured := ""
+	if operation.OperationID != "" {
+		configured = builder.options.Streaming.Operations[operation.OperationID]
+	}
+
+	var statusTexts []string
+	for statusText := range operation.Responses {
+		if strings.HasPrefix(statusText, "2") {
+			statusTexts = append(statusTexts, statusText)
+		}
+	}
+	sort.Strings(statusTexts)
+
+	var result *streamContent
+	only := true
+	for _, statusText := range statusTexts {
+		status, err := strconv.Atoi(statusText)
+		if err != nil {
+			continue
+		}
+		response := operation.Responses[statusText]
+		if response.Value == nil || response.Value.Content == nil {
+			continue
+		}
+
+		var mediaTypes []string
+		for mediaType := range response.Value.Content {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+		sort.Strings(mediaTypes)
+		for _, mediaType := range mediaTypes {
+			format, ok := streamMediaTypeFormat(mediaType)
+			if !ok {
+				only = false
+				continue
+			}
+			if result == nil {
+				result = &streamContent{mediaType: mediaType, content: response.Value.Content[mediaType], format: format}
+			}
+			if result.mediaType == mediaType {
+				result.status = append(result.status, status)
+			}
+		}
+
+		if configured != "" && result == nil {
+			format := ndjsonStream
+			switch configured {
+			case StreamFormatNDJSON:
+			case StreamFormatSSE:
+				format = sseStream
+			default:
+				return nil, errors.Errorf("invalid stream format: %s", configured)
+			}
+			mediaType, content, _ := builder.responseContent(response.Value.Content)
+			result = &streamContent{mediaType: mediaType, content: content, format: format, status: []int{status}}
+		}
+	}
+	if result != nil {
+		result.only = only || configured != ""
+	}
+	return result, nil
+}
+
+// getStreamEventType returns the GraphQL type of the events of the stream.
+func (builder *builder) getStreamEventType(stream *streamContent, typePath string) (schema.Type, error) {
+	if stream.content == nil || stream.content.Schema == nil {
+		if stream.format == sseStream {
+			return builder.draft.Types["String"], nil
+		}
+		return builder.JSONType(), nil
+	}
+
+	// kubernetes list operations stream watch events of the list items.
+	if items := watchListItems(stream); items != nil {
+		itemType, err := builder.addGraphQLType(items, typePath+"/object", false)
+		if err != nil {
+			return nil, err
+		}
+		name := typePath + "Event"
+		if t, ok := builder.draft.Types[name]; ok {
+			return t, nil
+		}
+		event := &schema.Object{
+			Name: name,
+			Desc: desc("A watch event of a " + schema.DeepestType(itemType).String()),
+			Fields: schema.FieldList{
+				&schema.Field{Name: "object", Type: itemType},
+				&schema.Field{Name: "type", Type: builder.draft.Types["String"]},
+			},
+		}
+		builder.draft.Types[name] = event
+		return event, nil
+	}
+	return builder.addGraphQLType(stream.content.Schema, typePath, false)
+}
+
+// watchListItems returns the items schema when the stream is a watch of a list operation.
+func watchListItems(stream *streamContent) *openapi3.SchemaRef {
+	if stream.format != watchStream || stream.content == nil || stream.content.Schema == nil || stream.content.Schema.Value == nil {
+		return nil
+	}
+	items := stream.content.Schema.Value.Properties["items"]
+	if items == nil || items.Value == nil || items.Value.Type != "array" || items.Value.Items == nil {
+		return nil
+	}
+	return items.Value.Items
+}
+
+// isWatchParam reports if the parameter is the kubernetes list operation parameter that turns on watching.
+func isWatchParam(stream *streamContent, param *openapi3.Parameter) bool {
+	return stream != nil && stream.format == watchStream && param.In == "query" && param.Name == "watch"
+}
+
+type streamResolver struct {
+	*resolver
+	operation *openapi3.Operation
+	stream    *streamContent
+}
+
+// Resolve calls the operation and fires a subscription event for every event read from the streamed
+// response.  The upstream connection is closed when the subscription ends.
+func (r *streamResolver) Resolve(request *resolvers.ResolveRequest, _ resolvers.Resolution) resolvers.Resolution {
+	return func() (reflect.Value, error) {
+		ctx := request.ExecutionContext.GetContext()
+		streamRequest := *request
+		streamRequest.Context = ctx
+		for _, param := range r.operation.Parameters {
+			if isWatchParam(r.stream, param.Value) {
+				args := map[string]interface{}{}
+				for k, v := range request.Args {
+					args[k] = v
+				}
+				args[r.options.fieldName(param.Value.Name)] = true
+				streamRequest.Args = args
+			}
+		}
+
+		value, err := r.call(&streamRequest, r.operation, r.stream.status, r.stream)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		events := value.Interface().(*eventStream)
+
+		go func() {
+			defer request.ExecutionContext.FireSubscriptionClose()
+			defer events.Close()
+			for {
+				event, err := events.next()
+				if ctx.Err() != nil || err == io.EOF {
+					return
+				}
+				if err != nil {
+					request.ExecutionContext.FireSubscriptionEvent(reflect.Value{}, err)
+					return
+				}
+				r.fireSubscriptionEvent(request, reflect.ValueOf(event))
+			}
+		}()
+		return reflect.Value{}, nil
+	}
+}
+
+// eventStream incrementally reads the events of a streamed response body.
+type eventStream struct {
+	body    io.ReadCloser
+	format  streamFormat
+	decoder *json.Decoder
+	scanner *bufio.Scanner
+}
+
+func newEventStream(body io.ReadCloser, format streamFormat) *eventStream {
+	s := &eventStream{body: body, format: format}
+	if format == sseStream {
+		s.scanner = bufio.NewScanner(body)
+		s.scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	} else {
+		s.decoder = json.NewDecoder(body)
+	}
+	return s
+}
+
+func (s *eventStream) Close() error {
+	return s.body.Close()
+}
+
+// next blocks until the next event is received.  It returns io.EOF at the end of the stream.
+func (s *eventStream) next() (interface{}, error) {
+	if s.format != sseStream {
+		var event interface{}
+		err := s.decoder.Decode(&event)
+		if err != nil {
+			if err == io.EOF {
+				return nil, err
+			}
+			return nil, errors.WithStack(err)
+		}
+		return event, nil
+	}
+
+	// See https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+	var data []string
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		if line == "" {
+			if data == nil {
+				continue
+			}
+			text := strings.Join(data, "\n")
+			var event interface{}
+			if err := json.Unmarshal([]byte(text), &event); err != nil {
+				return text, nil
+			}
+			return event, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // a comment
+		}
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		if field == "data" {
+			data = append(data, value)
+		}
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return nil, io.EOF
+}
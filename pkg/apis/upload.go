@@ -0,0 +1,377 @@
+package apis
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/chirino/graphql"
+	"github.com/pkg/errors"
+)
+
+// uploadMemoryLimit is the size after which received files are spooled to a temporary file.
+const uploadMemoryLimit = 1 << 20
+
+// Upload is a file received using the GraphQL multipart request spec
+// (https://github.com/jaydenseric/graphql-multipart-request-spec).  It's the value
+// held by variables of the Upload scalar type.
+//
+// The contents of the file can be read while they are received from the client request.
+// Every variable path of the file gets its own Upload, so that the file can be read once
+// for each of them.
+type Upload struct {
+	file   *uploadFile
+	offset int64
+	closed bool
+}
+
+// Filename returns the name of the uploaded file.  It blocks until the file part has been received.
+func (u *Upload) Filename() string {
+	<-u.file.received
+	return u.file.filename
+}
+
+// ContentType returns the content type of the uploaded file.  It blocks until the file part has been received.
+func (u *Upload) ContentType() string {
+	<-u.file.received
+	if u.file.contentType == "" {
+		return "application/octet-stream"
+	}
+	return u.file.contentType
+}
+
+// Read reads the file, it blocks until the next bytes of the file have been received.
+func (u *Upload) Read(p []byte) (int, error) {
+	return u.file.readAt(u, p)
+}
+
+// Close discards the rest of the upload.
+func (u *Upload) Close() error {
+	u.file.mu.Lock()
+	defer u.file.mu.Unlock()
+	u.closed = true
+	u.file.cond.Broadcast()
+	return nil
+}
+
+// uploadFile holds the content of a file part while it is received.  The parts are received in the
+// order the client sent them, and they are spooled in memory, or in a temporary file once they get
+// large, so that receiving a part never waits for the resolvers to read the previous ones.
+type uploadFile struct {
+	filename    string
+	contentType string
+	received    chan struct{}
+	once        sync.Once
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	data []byte
+	// spool holds the data once it's larger than the uploadMemoryLimit.
+	spool *os.File
+	size  int64
+	done  bool
+	err   error
+	// discard drops the data received once no one can read it anymore.
+	discard bool
+}
+
+func newUploadFile() *uploadFile {
+	f := &uploadFile{received: make(chan struct{})}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+func (f *uploadFile) newUpload() *Upload {
+	return &Upload{file: f}
+}
+
+func (f *uploadFile) markReceived(filename string, contentType string) {
+	f.once.Do(func() {
+		f.filename = filename
+		f.contentType = contentType
+		close(f.received)
+	})
+}
+
+func (f *uploadFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.discard {
+		return len(p), nil
+	}
+	if f.spool == nil && len(f.data)+len(p) > uploadMemoryLimit {
+		spool, err := ioutil.TempFile("", "graphql-4-apis-upload-")
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		f.spool = spool
+		if _, err := spool.Write(f.data); err != nil {
+			return 0, errors.WithStack(err)
+		}
+		f.data = nil
+	}
+	if f.spool != nil {
+		if _, err := f.spool.WriteAt(p, f.size); err != nil {
+			return 0, errors.WithStack(err)
+		}
+	} else {
+		f.data = append(f.data, p...)
+	}
+	f.size += int64(len(p))
+	f.cond.Broadcast()
+	return len(p), nil
+}
+
+// finish marks the end of the file, the readers get err once they read all the data, or io.EOF if nil.
+func (f *uploadFile) finish(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.done {
+		f.done = true
+		f.err = err
+		f.cond.Broadcast()
+	}
+}
+
+func (f *uploadFile) readAt(u *Upload, p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for u.offset >= f.size && !f.done && !u.closed {
+		f.cond.Wait()
+	}
+	if u.closed {
+		return 0, errors.New("upload closed")
+	}
+	if u.offset >= f.size {
+		if f.err != nil {
+			return 0, f.err
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > f.size-u.offset {
+		p = p[:f.size-u.offset]
+	}
+	var n int
+	if f.spool != nil {
+		var err error
+		n, err = f.spool.ReadAt(p, u.offset)
+		if err != nil && err != io.EOF {
+			return n, errors.WithStack(err)
+		}
+	} else {
+		n = copy(p, f.data[u.offset:])
+	}
+	u.offset += int64(n)
+	return n, nil
+}
+
+// release drops the received data, the file can not be read anymore.
+func (f *uploadFile) release() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.discard = true
+	f.data = nil
+	if f.spool != nil {
+		_ = f.spool.Close()
+		_ = os.Remove(f.spool.Name())
+		f.spool = nil
+	}
+	if !f.done {
+		f.done = true
+		f.err = errors.New("upload closed")
+	}
+	f.size = 0
+	f.cond.Broadcast()
+}
+
+func (u *Upload) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("an Upload can only be sent in a multipart request body")
+}
+
+// MultipartHandler handles GraphQL requests that follow the GraphQL multipart request
+// spec and delegates all other requests to the next http.Handler.
+type MultipartHandler struct {
+	Next                http.Handler
+	ServeGraphQLStream  graphql.ServeGraphQLStreamFunc
+	MaxRequestSizeBytes int64
+	Indent              string
+}
+
+func (h *MultipartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if r.Method != http.MethodPost || mediaType != "multipart/form-data" {
+		h.Next.ServeHTTP(w, r)
+		return
+	}
+	defer r.Body.Close()
+
+	if h.MaxRequestSizeBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.MaxRequestSizeBytes)
+	}
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The operations and map fields must be the first two parts.
+	request := graphql.Request{}
+	part, err := reader.NextPart()
+	if err != nil || part.FormName() != "operations" {
+		http.Error(w, "expected the first multipart field to be 'operations'", http.StatusBadRequest)
+		return
+	}
+	if err := json.NewDecoder(part).Decode(&request); err != nil {
+		http.Error(w, "invalid 'operations' field: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	part, err = reader.NextPart()
+	if err != nil || part.FormName() != "map" {
+		http.Error(w, "expected the second multipart field to be 'map'", http.StatusBadRequest)
+		return
+	}
+	fileMap := map[string][]string{}
+	if err := json.NewDecoder(part).Decode(&fileMap); err != nil {
+		http.Error(w, "invalid 'map' field: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	variables, err := request.VariablesAsMap()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if variables == nil {
+		variables = map[string]interface{}{}
+	}
+
+	uploads := map[string]*uploadFile{}
+	defer func() {
+		for _, file := range uploads {
+			file.release()
+		}
+	}()
+	for key, paths := range fileMap {
+		file := newUploadFile()
+		uploads[key] = file
+		for _, path := range paths {
+			if err := setUploadVariable(variables, path, file.newUpload()); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	request.Variables = variables
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	ctx = context.WithValue(ctx, "net/http.ResponseWriter", w)
+	ctx = context.WithValue(ctx, "*net/http.Request", r)
+	request.Context = ctx
+
+	responses := make(chan *graphql.Response, 1)
+	go func() {
+		responses <- h.ServeGraphQLStream.ServeGraphQL(&request)
+	}()
+
+	// Stream the file parts to the resolvers while the request executes..
+	streamErr := make(chan error, 1)
+	go func() {
+		streamErr <- streamUploads(reader, uploads)
+	}()
+
+	var response *graphql.Response
+	select {
+	case response = <-responses:
+		// don't keep the rest of the files that the resolvers did not read.
+		for _, file := range uploads {
+			file.release()
+		}
+		<-streamErr
+	case err := <-streamErr:
+		response = <-responses
+		if err != nil {
+			response.AddError(err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", h.Indent)
+	if err := encoder.Encode(response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func streamUploads(reader *multipart.Reader, uploads map[string]*uploadFile) error {
+	defer func() {
+		for _, file := range uploads {
+			file.markReceived("", "")
+			file.finish(errors.New("file was not included in the multipart request"))
+		}
+	}()
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		file := uploads[part.FormName()]
+		if file == nil {
+			_, _ = io.Copy(ioutil.Discard, part)
+			continue
+		}
+		file.markReceived(part.FileName(), part.Header.Get("Content-Type"))
+		_, err = io.Copy(file, part)
+		file.finish(errors.WithStack(err))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+}
+
+// setUploadVariable sets the upload at an object path like `variables.files.0`
+func setUploadVariable(variables map[string]interface{}, path string, upload *Upload) error {
+	keys := strings.Split(path, ".")
+	if len(keys) < 2 || keys[0] != "variables" {
+		return errors.Errorf("invalid upload path: %s", path)
+	}
+	keys = keys[1:]
+
+	var current interface{} = variables
+	for i, key := range keys {
+		last := i == len(keys)-1
+		switch c := current.(type) {
+		case map[string]interface{}:
+			if last {
+				c[key] = upload
+				return nil
+			}
+			current = c[key]
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(c) {
+				return errors.Errorf("invalid upload path: %s", path)
+			}
+			if last {
+				c[index] = upload
+				return nil
+			}
+			current = c[index]
+		default:
+			return errors.Errorf("invalid upload path: %s", path)
+		}
+	}
+	return nil
+}
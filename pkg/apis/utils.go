@@ -3,10 +3,12 @@ package apis
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strings"
 	"text/template"
 
 	"github.com/chirino/graphql/schema"
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/pkg/errors"
 )
 
@@ -80,3 +82,30 @@ func renderTemplate(variables interface{}, templateText string) (string, error)
 func desc(text string) schema.Description {
 	return schema.NewDescription(strings.TrimSpace(text))
 }
+
+func sortedPaths(paths openapi3.Paths) []string {
+	result := make([]string, 0, len(paths))
+	for path := range paths {
+		result = append(result, path)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func sortedMethods(operations map[string]*openapi3.Operation) []string {
+	result := make([]string, 0, len(operations))
+	for method := range operations {
+		result = append(result, method)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func sortedSchemaNames(schemas openapi3.Schemas) []string {
+	result := make([]string, 0, len(schemas))
+	for name := range schemas {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,118 @@
+package apis
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/chirino/graphql/qerrors"
+	"github.com/chirino/graphql/resolvers"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Violation describes an argument value that does not satisfy the constraints of its openapi schema.
+type Violation struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// validateArgs checks the arguments of a request against the schema constraints of the operation's
+// parameters and request body.  It reports all the violations found in a single GraphQL error.
+func (resolver *resolver) validateArgs(gqlRequest *resolvers.ResolveRequest, operation *openapi3.Operation) error {
+	violations := []Violation{}
+	check := func(name string, s *openapi3.SchemaRef, value interface{}) {
+		if s == nil || s.Value == nil {
+			return
+		}
+		if arg := gqlRequest.Field.Args.Get(name); arg != nil {
+			converted, err := resolver.inputConverters.Convert(arg.Type, value, name)
+			if err != nil {
+				violations = append(violations, Violation{Path: name, Reason: err.Error()})
+				return
+			}
+			value = converted
+		}
+		err := s.Value.VisitJSON(toJSONValue(value), openapi3.MultiErrors(), openapi3.VisitAsRequest())
+		violations = appendViolations(violations, name, err)
+	}
+
+	for _, param := range operation.Parameters {
+		qlid := resolver.options.fieldName(param.Value.Name)
+		if value, found := gqlRequest.Args[qlid]; found && value != nil {
+			check(qlid, getSchema(param.Value), value)
+		}
+	}
+	if _, content := requestBodyContent(operation); content != nil {
+		if value, found := gqlRequest.Args["body"]; found && value != nil {
+			check("body", content.Schema, value)
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Path < violations[j].Path
+	})
+	return qerrors.Errorf("invalid arguments: %s", violations[0].Path+": "+violations[0].Reason).
+		WithExtensions(map[string]interface{}{
+			"violations": violations,
+		})
+}
+
+func appendViolations(violations []Violation, path string, err error) []Violation {
+	switch err := err.(type) {
+	case nil:
+	case openapi3.MultiError:
+		for _, e := range err {
+			violations = appendViolations(violations, path, e)
+		}
+	case *openapi3.SchemaError:
+		pointer := err.JSONPointer()
+		if len(pointer) > 0 {
+			path = path + "." + strings.Join(pointer, ".")
+		}
+		violations = append(violations, Violation{Path: path, Reason: err.Reason})
+	default:
+		violations = append(violations, Violation{Path: path, Reason: err.Error()})
+	}
+	return violations
+}
+
+// toJSONValue normalizes GraphQL argument values to the types produced by the json decoder
+// so that they can be validated against openapi schemas.
+func toJSONValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			result[k] = toJSONValue(v)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, v := range value {
+			result[i] = toJSONValue(v)
+		}
+		return result
+	case *Upload:
+		// the contents of uploads can't be validated without consuming them.
+		return ""
+	case json.RawMessage:
+		var result interface{}
+		if err := json.Unmarshal(value, &result); err != nil {
+			return string(value)
+		}
+		return result
+	case int:
+		return float64(value)
+	case int32:
+		return float64(value)
+	case int64:
+		return float64(value)
+	case float32:
+		return float64(value)
+	default:
+		return value
+	}
+}
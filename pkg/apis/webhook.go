@@ -0,0 +1,371 @@
+package apis
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/chirino/graphql/resolvers"
+	"github.com/chirino/graphql/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+)
+
+// webhooksExtension holds the openapi 3.1 webhooks of the document as openapi3.Paths keyed by webhook name.
+const webhooksExtension = "webhooks"
+
+// DefaultSignatureHeader is the header holding the HMAC signature of the webhook requests, when the
+// SignatureHeader option is not set.
+const DefaultSignatureHeader = "X-Webhook-Signature"
+
+// SecretHeader is the header the API can send the webhook secret in, when it does not sign its requests.
+const SecretHeader = "X-Webhook-Secret"
+
+// WebhookOptions configures how the requests sent to the webhook receiver are authenticated.
+type WebhookOptions struct {
+	// Enabled exposes the callbacks and webhooks as subscriptions, the serve command then mounts the receiver
+	// at /webhooks.
+	Enabled bool `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	// Secret authenticates the webhook requests.  The API sends it in the X-Webhook-Secret header, or signs
+	// the payloads with it: the signature header holds the hex encoded HMAC-SHA256 of the payload, optionally
+	// prefixed with `sha256=`.
+	Secret string `yaml:"secret,omitempty" json:"secret,omitempty"`
+	// SignatureHeader is the header holding the payload signature, defaults to X-Webhook-Signature.
+	SignatureHeader string `yaml:"signature-header,omitempty" json:"signature-header,omitempty"`
+}
+
+// WebhookReceiver is the http.Handler that receives the requests the API sends to the callbacks
+// and webhooks described in the openapi document.  Received events are validated against the
+// callback request body schema and delivered to the subscribers of the matching subscription field.
+//
+// Configure it in the Config.WebhookReceiver field and mount it where the API can reach it, like
+// `http.Handle("/webhooks/", http.StripPrefix("/webhooks", receiver))`.  Each callback is received
+// at the `/{subscription field}` path relative to where the receiver is mounted.  Requests are only
+// accepted when they are authenticated with the Config.Webhooks secret.
+type WebhookReceiver struct {
+	// MaxRequestSizeBytes limits the size of the received payloads, defaults to 10MB.
+	MaxRequestSizeBytes int64
+	Log                 *log.Logger
+
+	mu       sync.RWMutex
+	webhooks map[string]*webhook
+	options  WebhookOptions
+}
+
+func NewWebhookReceiver() *WebhookReceiver {
+	return &WebhookReceiver{
+		webhooks: map[string]*webhook{},
+	}
+}
+
+type webhook struct {
+	method      string
+	mediaType   string
+	schema      *openapi3.SchemaRef
+	status      int
+	subscribers map[chan interface{}]bool
+	// text is set when the subscription field is a String, payloads that are not JSON are only accepted then.
+	text bool
+}
+
+func (receiver *WebhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(r.URL.Path, "/")
+	receiver.mu.RLock()
+	hook := receiver.webhooks[name]
+	options := receiver.options
+	receiver.mu.RUnlock()
+	if hook == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != hook.method {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	maxSize := receiver.MaxRequestSizeBytes
+	if maxSize <= 0 {
+		maxSize = 10 * 1024 * 1024
+	}
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !options.authenticated(r.Header, data) {
+		receiver.logf("rejecting webhook '%s' request: it is not authenticated", name)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "" {
+		mediaType = hook.mediaType
+	}
+	if !isJSONMediaType(mediaType) && !hook.text {
+		http.Error(w, "unsupported media type: "+mediaType+", expected a JSON payload", http.StatusUnsupportedMediaType)
+		return
+	}
+	event, err := hook.decode(mediaType, data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	receiver.mu.RLock()
+	for subscriber := range hook.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			receiver.logf("dropping webhook '%s' event: subscriber is not keeping up", name)
+		}
+	}
+	receiver.mu.RUnlock()
+	w.WriteHeader(hook.status)
+}
+
+// authenticated checks that a request holds the secret, or that its payload is signed with it.  Requests
+// are never authenticated when there is no secret.
+func (options WebhookOptions) authenticated(header http.Header, data []byte) bool {
+	if options.Secret == "" {
+		return false
+	}
+	if secret := header.Get(SecretHeader); secret != "" {
+		return subtle.ConstantTimeCompare([]byte(secret), []byte(options.Secret)) == 1
+	}
+	signatureHeader := options.SignatureHeader
+	if signatureHeader == "" {
+		signatureHeader = DefaultSignatureHeader
+	}
+	signature, err := hex.DecodeString(strings.TrimPrefix(header.Get(signatureHeader), "sha256="))
+	if err != nil || len(signature) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(options.Secret))
+	mac.Write(data)
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+// decode parses and validates a received payload.
+func (hook *webhook) decode(mediaType string, data []byte) (interface{}, error) {
+	if !isJSONMediaType(mediaType) {
+		return string(data), nil
+	}
+
+	var event interface{}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, errors.Wrap(err, "invalid json payload")
+	}
+	if hook.schema != nil && hook.schema.Value != nil {
+		err := hook.schema.Value.VisitJSON(event, openapi3.MultiErrors(), openapi3.VisitAsRequest())
+		if violations := appendViolations(nil, "body", err); len(violations) > 0 {
+			messages := []string{}
+			for _, v := range violations {
+				messages = append(messages, v.Path+": "+v.Reason)
+			}
+			return nil, errors.Errorf("invalid payload: %s", strings.Join(messages, ", "))
+		}
+	}
+	return event, nil
+}
+
+// replace swaps the webhooks received and their options with the ones of a new engine.  The subscribers
+// of the webhooks the new engine still has keep their subscription, the others are closed.
+func (receiver *WebhookReceiver) replace(webhooks map[string]*webhook, options WebhookOptions) {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	for name, existing := range receiver.webhooks {
+		if hook := webhooks[name]; hook != nil {
+			hook.subscribers = existing.subscribers
+			continue
+		}
+		for subscriber := range existing.subscribers {
+			delete(existing.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+	receiver.webhooks = webhooks
+	receiver.options = options
+}
+
+// subscribe returns the channel receiving the events of a webhook, or nil if it is not received anymore.
+func (receiver *WebhookReceiver) subscribe(name string) chan interface{} {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	hook := receiver.webhooks[name]
+	if hook == nil {
+		return nil
+	}
+	events := make(chan interface{}, 64)
+	hook.subscribers[events] = true
+	return events
+}
+
+func (receiver *WebhookReceiver) unsubscribe(name string, events chan interface{}) {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if hook := receiver.webhooks[name]; hook != nil {
+		delete(hook.subscribers, events)
+	}
+}
+
+func (receiver *WebhookReceiver) logf(format string, v ...interface{}) {
+	if receiver.Log != nil {
+		receiver.Log.Printf(format, v...)
+	}
+}
+
+// addCallbackFields exposes the callbacks of an operation as subscription fields.
+func (builder *builder) addCallbackFields(operation *openapi3.Operation) {
+	var names []string
+	for name := range operation.Callbacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		callback := operation.Callbacks[name]
+		if callback == nil || callback.Value == nil {
+			continue
+		}
+		prefix := builder.operationFieldName(operation) + capitalizeFirstLetter(builder.options.fieldName(name))
+		for expression, pathItem := range *callback.Value {
+			builder.addWebhookFields(prefix, operationPointer(operation)+"/callbacks/"+escapePointerToken(name)+"/"+escapePointerToken(expression), pathItem)
+		}
+	}
+}
+
+// addWebhookFields exposes the operations of a callback or webhook path item as subscription fields.
+func (builder *builder) addWebhookFields(prefix string, pointer string, pathItem *openapi3.PathItem) {
+	if pathItem == nil {
+		return
+	}
+	operations := pathItem.Operations()
+	var methods []string
+	for method := range operations {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		operation := operations[method]
+		fieldName := prefix
+		if operation.OperationID != "" {
+			fieldName = builder.options.fieldName(operation.OperationID)
+		} else if len(methods) > 1 {
+			fieldName = prefix + capitalizeFirstLetter(strings.ToLower(method))
+		}
+		err := builder.addWebhookField(fieldName, method, operation)
+		if err != nil {
+			builder.report(SeverityError, pointer+"/"+strings.ToLower(method), builder.options.SubscriptionType+"."+fieldName, "could not map webhook '%s' to a subscription: %s", fieldName, err)
+		}
+	}
+}
+
+func (builder *builder) addWebhookField(fieldName string, method string, operation *openapi3.Operation) error {
+	draft := builder.draft
+	subscriptionType := builder.options.SubscriptionType
+
+	var rootObject *schema.Object
+	if t, ok := draft.Types[subscriptionType]; ok {
+		rootObject = t.(*schema.Object)
+	} else {
+		rootObject = &schema.Object{
+			Name: subscriptionType,
+		}
+		draft.Types[subscriptionType] = rootObject
+	}
+	if rootObject.Fields.Get(fieldName) != nil {
+		return errors.Errorf("field already exists: %s", fieldName)
+	}
+
+	hook := &webhook{
+		method:      method,
+		status:      http.StatusOK,
+		subscribers: map[chan interface{}]bool{},
+	}
+	var statuses []string
+	for status := range operation.Responses {
+		if strings.HasPrefix(status, "2") {
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Strings(statuses)
+	if len(statuses) > 0 {
+		if status, err := strconv.Atoi(statuses[0]); err == nil {
+			hook.status = status
+		}
+	}
+
+	var fieldType schema.Type = builder.JSONType()
+	typePath := subscriptionType + capitalizeFirstLetter(fieldName)
+	if mediaType, content := requestBodyContent(operation); content != nil {
+		hook.mediaType = mediaType
+		hook.schema = content.Schema
+		if content.Schema != nil {
+			t, err := builder.addGraphQLType(content.Schema, typePath, false)
+			if err != nil {
+				return errors.Wrap(err, "request body type cannot be converted")
+			}
+			fieldType = t
+		} else if !isJSONMediaType(mediaType) {
+			fieldType = draft.Types["String"]
+		}
+	}
+	hook.text = fieldType == draft.Types["String"]
+
+	text := ""
+	if operation.Summary != "" {
+		text = operation.Summary + "\n"
+	}
+	if operation.Description != "" {
+		text = text + operation.Description + "\n"
+	}
+	text = text + "\n**receiver:** `" + method + " /" + fieldName + "`"
+	rootObject.Fields = append(rootObject.Fields, &schema.Field{
+		Name: fieldName,
+		Desc: desc(text),
+		Type: fieldType,
+	})
+
+	// the receiver gets the webhooks once the engine is built.
+	builder.webhooks[fieldName] = hook
+	receiver := builder.options.WebhookReceiver
+	builder.resolvers[subscriptionType+":"+fieldName] = resolvers.Func(func(request *resolvers.ResolveRequest, _ resolvers.Resolution) resolvers.Resolution {
+		return func() (reflect.Value, error) {
+			ctx := request.ExecutionContext.GetContext()
+			events := receiver.subscribe(fieldName)
+			if events == nil {
+				return reflect.Value{}, errors.Errorf("webhook '%s' is not received anymore", fieldName)
+			}
+			go func() {
+				defer request.ExecutionContext.FireSubscriptionClose()
+				defer receiver.unsubscribe(fieldName, events)
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case event, ok := <-events:
+						if !ok {
+							// the reloaded engine does not receive the webhook anymore.
+							return
+						}
+						builder.fireSubscriptionEvent(request, reflect.ValueOf(event))
+					}
+				}
+			}()
+			return reflect.Value{}, nil
+		}
+	})
+	return nil
+}